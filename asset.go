@@ -0,0 +1,359 @@
+package md
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// LocalAssetOptions configures the handler of `LocalAssetHandler`.
+type LocalAssetOptions struct {
+	// Dir is the directory that the assets are saved to. It is created if it does not exist.
+	Dir string
+
+	// PathPrefix is put in front of the file name in the markdown,
+	// it should be the path of `Dir` relative to the markdown file.
+	// default: `Dir`
+	PathPrefix string
+
+	// SourceDir is used to copy the assets with relative urls
+	// (e.g. "images/cat.png") if the converter has no domain.
+	// Files outside of the directory and "file:" urls are never read.
+	SourceDir string
+
+	// LinkExtensions are the file extensions (e.g. ".pdf") of the links
+	// that are also saved. By default only the images are saved.
+	LinkExtensions []string
+
+	// MaxConcurrent limits the number of assets that are downloaded at the same time.
+	// default: 4
+	MaxConcurrent int
+
+	// MaxSize is the maximum size of an asset in bytes.
+	// Bigger assets keep their original url.
+	// default: 50 MB
+	MaxSize int64
+
+	// Client is used for the downloads.
+	// default: a client with the `Timeout`
+	Client *http.Client
+}
+
+// errAssetTooBig is returned if the asset is bigger than `MaxSize`.
+var errAssetTooBig = errors.New("the asset is too big")
+
+type localAsset struct {
+	once sync.Once
+	path string
+}
+
+// LocalAssetHandler returns an `AssetHandler` that saves the images (and the linked
+// files with one of the `LinkExtensions`) into a local directory and rewrites the urls
+// to the relative paths. The files are named after the hash of their content, so the
+// same asset is only saved once. If the asset can't be saved the url stays the same
+// and it is tried again the next time the url is requested.
+func LocalAssetHandler(options *LocalAssetOptions) func(selec *goquery.Selection, rawURL string) string {
+	var assetOpt LocalAssetOptions
+	if options != nil {
+		assetOpt = *options
+	}
+	if assetOpt.PathPrefix == "" {
+		assetOpt.PathPrefix = filepath.ToSlash(assetOpt.Dir)
+	}
+	if assetOpt.MaxConcurrent <= 0 {
+		assetOpt.MaxConcurrent = 4
+	}
+	if assetOpt.MaxSize <= 0 {
+		assetOpt.MaxSize = 50 << 20
+	}
+	if assetOpt.Client == nil {
+		assetOpt.Client = &http.Client{Timeout: Timeout}
+	}
+
+	var (
+		mutex     sync.Mutex
+		assets    = make(map[string]*localAsset)
+		semaphore = make(chan struct{}, assetOpt.MaxConcurrent)
+	)
+
+	return func(selec *goquery.Selection, rawURL string) string {
+		if selec.Is("a") && !hasExtension(rawURL, assetOpt.LinkExtensions) {
+			return rawURL
+		}
+		if strings.HasPrefix(strings.ToLower(rawURL), "data:") {
+			return rawURL
+		}
+
+		// the same url is only saved once, even if it is requested at the same time
+		mutex.Lock()
+		asset, ok := assets[rawURL]
+		if !ok {
+			asset = &localAsset{}
+			assets[rawURL] = asset
+		}
+		mutex.Unlock()
+
+		asset.once.Do(func() {
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			name, err := saveAsset(&assetOpt, rawURL)
+			if err != nil {
+				// only the saved assets are remembered, a failed
+				// download (e.g. a timeout) can succeed later
+				mutex.Lock()
+				if assets[rawURL] == asset {
+					delete(assets, rawURL)
+				}
+				mutex.Unlock()
+				return
+			}
+			asset.path = path.Join(assetOpt.PathPrefix, name)
+		})
+
+		if asset.path == "" {
+			return rawURL
+		}
+		return asset.path
+	}
+}
+
+// saveAsset reads the asset and writes it into the directory.
+// It returns the name of the file.
+func saveAsset(opt *LocalAssetOptions, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	var data []byte
+	var contentType string
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+		data, contentType, err = downloadAsset(opt, rawURL)
+	case u.Scheme == "" && u.Host == "" && opt.SourceDir != "":
+		var name string
+		name, err = sourcePath(opt.SourceDir, u.Path)
+		if err == nil {
+			data, err = readAsset(opt, name)
+		}
+	default:
+		err = fmt.Errorf("the url %q can't be saved", rawURL)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	name := hex.EncodeToString(hash[:])[:16] + assetExtension(u.Path, contentType)
+
+	if err := os.MkdirAll(opt.Dir, 0755); err != nil {
+		return "", err
+	}
+	p := filepath.Join(opt.Dir, name)
+	if _, err := os.Stat(p); err == nil {
+		// the same content was already saved
+		return name, nil
+	}
+
+	// write to a temporary file first, to not leave a half written asset behind.
+	// The name is unique, since other handlers can write the same asset.
+	tmp, err := os.CreateTemp(filepath.Dir(p), name+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return name, nil
+}
+
+type assetKey struct {
+	node   *html.Node
+	rawURL string
+}
+
+// prefetchAssets calls the `AssetHandler` at the same time for all images and links
+// of the document, so that the downloads don't wait for each other. The rules
+// get the results through `AssetURL`.
+func prefetchAssets(selec *goquery.Selection, opt *Options) map[assetKey]string {
+	if opt.AssetHandler == nil {
+		return nil
+	}
+
+	var requests []assetKey
+	if opt.ImageStyle != "none" && opt.ImageStyle != "alt" {
+		selec.Find("img").Each(func(i int, s *goquery.Selection) {
+			src := imageSource(s, opt.ImagePreferredWidth)
+			if src != "" {
+				src = TransformURL(s, src, URLKindImage, opt)
+			}
+			if src != "" {
+				requests = append(requests, assetKey{node: s.Get(0), rawURL: src})
+			}
+		})
+	}
+	if opt.LinkStyle != "text" {
+		selec.Find("a[href]").Each(func(i int, s *goquery.Selection) {
+			href := s.AttrOr("href", "")
+			if strings.TrimSpace(href) == "" || strings.TrimSpace(href) == "#" {
+				return
+			}
+			if _, ok := fragmentLink(s, href, opt); ok {
+				return
+			}
+			if href = TransformURL(s, href, URLKindAnchor, opt); href != "" {
+				requests = append(requests, assetKey{node: s.Get(0), rawURL: href})
+			}
+		})
+	}
+
+	results := make([]string, len(requests))
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		wg.Add(1)
+		go func(i int, req assetKey) {
+			defer wg.Done()
+			results[i] = opt.AssetHandler(goquery.NewDocumentFromNode(req.node).Selection, req.rawURL)
+		}(i, req)
+	}
+	wg.Wait()
+
+	assets := make(map[assetKey]string, len(requests))
+	for i, req := range requests {
+		assets[req] = results[i]
+	}
+	return assets
+}
+
+// AssetURL passes the url through the `AssetHandler`. The images and links
+// of the document were already requested before the conversion.
+func AssetURL(selec *goquery.Selection, rawURL string, opt *Options) string {
+	if opt.AssetHandler == nil {
+		return rawURL
+	}
+	if len(selec.Nodes) > 0 {
+		if u, ok := opt.assets[assetKey{node: selec.Get(0), rawURL: rawURL}]; ok {
+			return u
+		}
+	}
+	return opt.AssetHandler(selec, rawURL)
+}
+
+// sourcePath returns the path of a relative url inside of the `SourceDir`.
+// Paths outside of it (e.g. "../../secret.txt") are rejected, since the
+// html could otherwise copy any local file into the output.
+func sourcePath(sourceDir string, urlPath string) (string, error) {
+	dir, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return "", err
+	}
+	name := filepath.Join(dir, filepath.FromSlash(urlPath))
+
+	rel, err := filepath.Rel(dir, name)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("the path %q is outside of the source directory", urlPath)
+	}
+	return name, nil
+}
+
+func downloadAsset(opt *LocalAssetOptions, rawURL string) ([]byte, string, error) {
+	resp, err := opt.Client.Get(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("expected a status code in the 2xx range but got %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, opt.MaxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > opt.MaxSize {
+		return nil, "", errAssetTooBig
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+func readAsset(opt *LocalAssetOptions, name string) ([]byte, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > opt.MaxSize {
+		return nil, errAssetTooBig
+	}
+	return os.ReadFile(name)
+}
+
+// assetExtension returns the extension of the path or else
+// the extension that belongs to the content type.
+func assetExtension(p, contentType string) string {
+	ext := strings.ToLower(path.Ext(p))
+	if ext != "" && len(ext) <= 6 && isAlphanumeric(ext[1:]) {
+		return ext
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "image/jpeg":
+		// the first extension of the mime package depends on the system
+		return ".jpg"
+	case "":
+		return ""
+	}
+	if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+func hasExtension(rawURL string, extensions []string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	for _, e := range extensions {
+		if ext != "" && strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func isAlphanumeric(s string) bool {
+	for _, r := range s {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,294 @@
+package md
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLocalAssetHandler(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		switch req.URL.Path {
+		case "/cat.png", "/copy-of-cat.png":
+			rw.Write([]byte("cat"))
+		case "/photo":
+			rw.Header().Set("Content-Type", "image/jpeg")
+			rw.Write([]byte("photo"))
+		case "/manual.pdf":
+			rw.Write([]byte("manual"))
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	conv := NewConverter("", true, &Options{
+		AssetHandler: LocalAssetHandler(&LocalAssetOptions{
+			Dir:            dir,
+			PathPrefix:     "assets",
+			LinkExtensions: []string{".pdf"},
+			Client:         server.Client(),
+		}),
+	})
+
+	input := fmt.Sprintf(`
+<p><img src="%[1]s/cat.png" alt="cat" /></p>
+<p><img src="%[1]s/cat.png" alt="same url" /></p>
+<p><img src="%[1]s/copy-of-cat.png" alt="same content" /></p>
+<p><img src="%[1]s/photo" alt="photo" /></p>
+<p><img src="%[1]s/missing.png" alt="missing" /></p>
+<p><a href="%[1]s/manual.pdf">manual</a> <a href="%[1]s/page.html">page</a></p>
+`, server.URL)
+
+	markdown, err := conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprintf(`![cat](assets/77af778b51abd4a3.png)
+
+![same url](assets/77af778b51abd4a3.png)
+
+![same content](assets/77af778b51abd4a3.png)
+
+![photo](assets/55c64d0fcd6f9d5f.jpg)
+
+![missing](%[1]s/missing.png)
+
+[manual](assets/36bde66f289a3568.pdf) [page](%[1]s/page.html)`, server.URL)
+	if markdown != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, markdown)
+	}
+
+	// the same url is only downloaded once
+	if requests != 5 {
+		t.Errorf("expected 5 requests but got %d", requests)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 3 files but got %d", len(entries))
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "77af778b51abd4a3.png"))
+	if err != nil || string(data) != "cat" {
+		t.Errorf("expected the content of the asset but got %q (%v)", data, err)
+	}
+}
+
+func TestLocalAssetHandler_SourceDir(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "images", "dog.gif"), []byte("dog"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	conv := NewConverter("", true, &Options{
+		AssetHandler: LocalAssetHandler(&LocalAssetOptions{
+			Dir:        dir,
+			PathPrefix: "assets",
+			SourceDir:  source,
+		}),
+	})
+
+	markdown, err := conv.ConvertString(`<p><img src="images/dog.gif" alt="dog" /></p><p><img src="images/cat.gif" alt="cat" /></p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "![dog](assets/cd6357efdd966de8.gif)\n\n![cat](images/cat.gif)"
+	if markdown != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, markdown)
+	}
+}
+
+func TestLocalAssetHandler_LocalFiles(t *testing.T) {
+	root := t.TempDir()
+	secret := filepath.Join(root, "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := filepath.Join(root, "site", "pages")
+	if err := os.MkdirAll(source, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	conv := NewConverter("", true, &Options{
+		AssetHandler: LocalAssetHandler(&LocalAssetOptions{
+			Dir:        dir,
+			PathPrefix: "assets",
+			SourceDir:  source,
+		}),
+	})
+
+	input := `<p><img src="file://` + filepath.ToSlash(secret) + `" alt="file" /></p>` +
+		`<p><img src="../../secret.txt" alt="relative" /></p>`
+	markdown, err := conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "![file](file://" + filepath.ToSlash(secret) + ")\n\n![relative](../../secret.txt)"
+	if markdown != expected {
+		t.Errorf("expected\n%s\nbut got\n%s", expected, markdown)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no saved assets but got %d", len(entries))
+	}
+}
+
+func TestLocalAssetHandler_MaxConcurrent(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+		rw.Write([]byte(req.URL.Path))
+	}))
+	defer server.Close()
+
+	conv := NewConverter("", true, &Options{
+		AssetHandler: LocalAssetHandler(&LocalAssetOptions{
+			Dir:           t.TempDir(),
+			MaxConcurrent: 2,
+			Client:        server.Client(),
+		}),
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			markdown, err := conv.ConvertString(fmt.Sprintf(`<img src="%s/%d.png" />`, server.URL, i))
+			if err != nil {
+				t.Error(err)
+			}
+			if strings.Contains(markdown, server.URL) {
+				t.Errorf("expected a local path but got %s", markdown)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent downloads but got %d", peak)
+	}
+}
+
+func TestLocalAssetHandler_ParallelPage(t *testing.T) {
+	var current, peak int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		n := atomic.AddInt32(&current, 1)
+		defer atomic.AddInt32(&current, -1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		rw.Write([]byte(req.URL.Path))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	conv := NewConverter("", true, &Options{
+		AssetHandler: LocalAssetHandler(&LocalAssetOptions{
+			Dir:           dir,
+			MaxConcurrent: 3,
+			Client:        server.Client(),
+		}),
+	})
+
+	var input strings.Builder
+	for i := 0; i < 9; i++ {
+		fmt.Fprintf(&input, `<p><img src="%s/%d.png" /></p>`, server.URL, i)
+	}
+	markdown, err := conv.ConvertString(input.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(markdown, server.URL) {
+		t.Errorf("expected local paths but got %s", markdown)
+	}
+
+	if peak < 2 {
+		t.Errorf("expected the images of a page to be downloaded at the same time")
+	}
+	if peak > 3 {
+		t.Errorf("expected at most 3 concurrent downloads but got %d", peak)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	if len(files) > 0 {
+		t.Errorf("expected no temporary files but got %v", files)
+	}
+}
+
+func TestLocalAssetHandler_RetryFailed(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte("cat"))
+	}))
+	defer server.Close()
+
+	conv := NewConverter("", true, &Options{
+		AssetHandler: LocalAssetHandler(&LocalAssetOptions{
+			Dir:    t.TempDir(),
+			Client: server.Client(),
+		}),
+	})
+	input := `<img src="` + server.URL + `/cat.png" />`
+
+	markdown, err := conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(markdown, server.URL) {
+		t.Errorf("expected the original url after the failed download but got %s", markdown)
+	}
+
+	markdown, err = conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(markdown, server.URL) {
+		t.Errorf("expected the download to be tried again but got %s", markdown)
+	}
+}
@@ -0,0 +1,355 @@
+package md
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// codeLanguageAliases maps the names that the syntax highlighters
+// use to the names that are understood by most markdown renderers.
+var codeLanguageAliases = map[string]string{
+	"js":            "javascript",
+	"jsx":           "jsx",
+	"mjs":           "javascript",
+	"cjs":           "javascript",
+	"node":          "javascript",
+	"ts":            "typescript",
+	"tsx":           "tsx",
+	"py":            "python",
+	"py3":           "python",
+	"python3":       "python",
+	"rb":            "ruby",
+	"sh":            "bash",
+	"shell":         "bash",
+	"zsh":           "bash",
+	"shellscript":   "bash",
+	"console":       "console",
+	"shellsession":  "console",
+	"shell-session": "console",
+	"ps":            "powershell",
+	"ps1":           "powershell",
+	"pwsh":          "powershell",
+	"bat":           "batch",
+	"cmd":           "batch",
+	"yml":           "yaml",
+	"md":            "markdown",
+	"mdx":           "mdx",
+	"markup":        "html",
+	"xhtml":         "html",
+	"htm":           "html",
+	"svg":           "xml",
+	"c++":           "cpp",
+	"cc":            "cpp",
+	"hpp":           "cpp",
+	"h":             "c",
+	"cs":            "csharp",
+	"c#":            "csharp",
+	"f#":            "fsharp",
+	"golang":        "go",
+	"kt":            "kotlin",
+	"kts":           "kotlin",
+	"rs":            "rust",
+	"objc":          "objectivec",
+	"objective-c":   "objectivec",
+	"docker":        "dockerfile",
+	"tf":            "hcl",
+	"terraform":     "hcl",
+	"gql":           "graphql",
+	"proto":         "protobuf",
+	"patch":         "diff",
+	"make":          "makefile",
+	"mk":            "makefile",
+	"ex":            "elixir",
+	"exs":           "elixir",
+	"erl":           "erlang",
+	"hs":            "haskell",
+	"pl":            "perl",
+	"jl":            "julia",
+	"vb":            "vbnet",
+	"sass":          "sass",
+	"styl":          "stylus",
+	"psql":          "sql",
+	"postgres":      "sql",
+	"postgresql":    "sql",
+	"mysql":         "sql",
+	"plsql":         "sql",
+	"tex":           "latex",
+	"ini":           "ini",
+	"cfg":           "ini",
+	"toml":          "toml",
+	"jsonc":         "json",
+	"json5":         "json5",
+	"text":          "",
+	"txt":           "",
+	"plain":         "",
+	"plaintext":     "",
+	"none":          "",
+	"nohighlight":   "",
+	"no-highlight":  "",
+}
+
+// codeLanguages are the languages that are also recognized as a class
+// without a prefix, for example `<code class="hljs python">`.
+var codeLanguages = map[string]bool{
+	"bash": true, "c": true, "clojure": true, "cpp": true, "csharp": true, "css": true,
+	"dart": true, "diff": true, "dockerfile": true, "elixir": true, "elm": true, "erlang": true,
+	"fsharp": true, "go": true, "graphql": true, "groovy": true, "haskell": true, "hcl": true,
+	"html": true, "http": true, "ini": true, "java": true, "javascript": true, "json": true,
+	"jsx": true, "julia": true, "kotlin": true, "latex": true, "less": true, "lua": true,
+	"makefile": true, "markdown": true, "matlab": true, "nginx": true, "nim": true, "ocaml": true,
+	"perl": true, "php": true, "powershell": true, "protobuf": true, "python": true, "r": true,
+	"ruby": true, "rust": true, "scala": true, "scss": true, "solidity": true, "sql": true,
+	"swift": true, "toml": true, "tsx": true, "typescript": true, "vbnet": true, "vue": true,
+	"xml": true, "yaml": true, "zig": true,
+}
+
+// codeLanguagePrefixes are the prefixes of the classes of the syntax highlighters:
+// Prism, highlight.js, Rouge (`language-`), Pygments/Sphinx (`highlight-`)
+// and GitHub (`highlight-source-`).
+var codeLanguagePrefixes = []string{
+	"language-", "lang-", "highlight-source-", "highlight-", "brush:",
+}
+
+// codeHighlighterClasses are used next to a class with the
+// language that has no prefix, for example "hljs python".
+var codeHighlighterClasses = map[string]bool{
+	"hljs": true, "sourcecode": true, "highlight": true, "prettyprint": true, "shiki": true,
+}
+
+var codeLanguageAttributes = []string{"data-lang", "data-language", "data-code-language"}
+
+var codeLanguageR = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}+#.-]*$`)
+
+// DefaultGetCodeBlockLanguage returns the language of the code block from the
+// `data-lang` attribute or the classes of the syntax highlighters (Prism, highlight.js,
+// Rouge, Pygments, Shiki, GitHub, ...) on the `<pre>`, `<code>` and their wrappers.
+// The language is returned as it is written, see `NormalizeCodeBlockLanguage`.
+func DefaultGetCodeBlockLanguage(s *goquery.Selection, content string) string {
+	elements := []*goquery.Selection{s.Find("code").First(), s}
+	// e.g. `<div class="language-ruby highlighter-rouge"><div class="highlight"><pre>`
+	parent := s.Parent()
+	for i := 0; i < 2 && parent.Length() > 0 && !parent.Is("body"); i++ {
+		elements = append(elements, parent)
+		parent = parent.Parent()
+	}
+
+	for _, e := range elements {
+		if e.Length() == 0 {
+			continue
+		}
+		if language, ok := elementCodeLanguage(e); ok {
+			return language
+		}
+	}
+	return ""
+}
+
+// elementCodeLanguage returns the language of the attributes or classes
+// and whether it was specified.
+func elementCodeLanguage(e *goquery.Selection) (string, bool) {
+	class := e.AttrOr("class", "")
+	// SyntaxHighlighter: `class="brush: js; toolbar: false"`
+	class = strings.ReplaceAll(class, "brush: ", "brush:")
+	class = strings.ReplaceAll(class, ";", " ")
+	fields := strings.Fields(class)
+
+	var isHighlighted bool
+	for _, field := range fields {
+		if codeHighlighterClasses[strings.ToLower(field)] {
+			isHighlighted = true
+		}
+	}
+	isCode := e.Is("pre, code")
+
+	// The attributes of a wrapper are only used next to a highlighter,
+	// since e.g. `data-lang="en"` is also used for translations.
+	if isCode || isHighlighted {
+		for _, attr := range codeLanguageAttributes {
+			if language, ok := cleanCodeLanguage(e.AttrOr(attr, "")); ok {
+				return language, true
+			}
+		}
+	}
+
+	for _, field := range fields {
+		for _, prefix := range codeLanguagePrefixes {
+			if !hasPrefixFold(field, prefix) {
+				continue
+			}
+			// e.g. "language-lang-go"
+			value := field[len(prefix):]
+			for _, p := range codeLanguagePrefixes {
+				if hasPrefixFold(value, p) {
+					value = value[len(p):]
+				}
+			}
+			if language, ok := cleanCodeLanguage(value); ok {
+				return language, true
+			}
+		}
+	}
+
+	// a class without prefix, for example "hljs python" or "go". Aliases
+	// are only used next to a highlighter, since short classes like "mk" are
+	// common for minified css.
+	if !isHighlighted && !isCode {
+		return "", false
+	}
+	for _, field := range fields {
+		language, ok := cleanCodeLanguage(field)
+		lower := strings.ToLower(language)
+		_, isAlias := codeLanguageAliases[lower]
+		if ok && (codeLanguages[lower] || (isHighlighted && isAlias)) {
+			return language, true
+		}
+	}
+	return "", false
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// cleanCodeLanguage returns the language if it is a valid name for the info string.
+func cleanCodeLanguage(language string) (string, bool) {
+	language = strings.TrimSpace(language)
+	if language == "" || codeHighlighterClasses[strings.ToLower(language)] || !codeLanguageR.MatchString(language) {
+		return "", false
+	}
+	return language, true
+}
+
+// NormalizeCodeBlockLanguage returns the name of the language from the alias
+// table, for example "python" for "py". The languages for plain text
+// (e.g. "text" or "none") are returned as an empty string.
+func NormalizeCodeBlockLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := codeLanguageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// CodeBlockLanguage returns the language of the code block with the
+// `GetCodeBlockLanguage` of the options. The language is normalized with
+// `NormalizeCodeBlockLanguage` and if no language was found, it is guessed
+// from the content if these options are enabled.
+func CodeBlockLanguage(s *goquery.Selection, content string, opt *Options) string {
+	getLanguage := opt.GetCodeBlockLanguage
+	if getLanguage == nil {
+		getLanguage = DefaultGetCodeBlockLanguage
+	}
+
+	language := getLanguage(s, content)
+	if opt.NormalizeCodeBlockLanguage {
+		language = NormalizeCodeBlockLanguage(language)
+	}
+	if language == "" && opt.GuessCodeBlockLanguage {
+		language = GuessCodeBlockLanguage(content)
+	}
+	return language
+}
+
+type languageHint struct {
+	language string
+	r        *regexp.Regexp
+	score    int
+}
+
+// languageHints are the patterns that are typical for a language.
+var languageHints = []languageHint{
+	{"go", regexp.MustCompile(`(?m)^package \w+$`), 3},
+	{"go", regexp.MustCompile(`(?m)^func (\(\w+ \*?\w+\) )?\w+\(`), 3},
+	{"go", regexp.MustCompile(`\w+ := `), 1},
+	{"go", regexp.MustCompile(`\bfmt\.\w+\(`), 2},
+
+	{"python", regexp.MustCompile(`(?m)^\s*def \w+\(.*\):\s*$`), 3},
+	{"python", regexp.MustCompile(`(?m)^(from [\w.]+ )?import [\w., ]+$`), 1},
+	{"python", regexp.MustCompile(`(?m)^\s*(if|for|while|elif|else|try|except|with) ?.*:\s*$`), 1},
+	{"python", regexp.MustCompile(`\bprint\(|\bself\.|__init__|\bNone\b|\bTrue\b`), 1},
+
+	{"javascript", regexp.MustCompile(`\b(const|let|var) \w+ = `), 1},
+	{"javascript", regexp.MustCompile(`=> ?[{(\w]`), 1},
+	{"javascript", regexp.MustCompile(`\bconsole\.log\(|\brequire\(['"]|\bdocument\.\w+|\bmodule\.exports\b`), 2},
+	{"javascript", regexp.MustCompile(`(?m)^\s*(export |import .* from ['"])`), 1},
+
+	{"typescript", regexp.MustCompile(`(?m)^\s*(export )?(interface|type) \w+ (= |\{)`), 3},
+	{"typescript", regexp.MustCompile(`\w: (string|number|boolean|any)\b`), 2},
+
+	{"bash", regexp.MustCompile(`(?m)^#!/(usr/)?bin/(env )?(ba|z)?sh`), 5},
+	{"bash", regexp.MustCompile(`(?m)^\s*(sudo |apt(-get)? |brew |npm |yarn |pip3? |go (get|install|run) |cd |mkdir |export \w+=|curl |wget |git |docker |kubectl )`), 2},
+	{"bash", regexp.MustCompile(`(?m)^\s*echo `), 1},
+
+	{"html", regexp.MustCompile(`(?i)<!doctype html|<html[\s>]`), 5},
+	{"html", regexp.MustCompile(`</(div|span|p|a|body|head|ul|li|script|section)>`), 2},
+
+	{"css", regexp.MustCompile(`(?m)^\s*[.#@]?[\w-][\w\s.#:>,-]*\{\s*$`), 1},
+	{"css", regexp.MustCompile(`(?m)^\s*[a-z-]+: [^;]+;\s*$`), 1},
+
+	{"sql", regexp.MustCompile(`(?i)\b(select [\w*, .]+ from|insert into|update \w+ set|create table|delete from)\b`), 4},
+
+	{"yaml", regexp.MustCompile(`(?m)^[\w-]+:( [^{};]+)?$`), 1},
+	{"yaml", regexp.MustCompile(`(?m)^\s+- [\w"']`), 1},
+	{"yaml", regexp.MustCompile(`(?m)^---$`), 1},
+
+	{"java", regexp.MustCompile(`\b(public|private|protected) (static )?(class|void|final) `), 3},
+	{"java", regexp.MustCompile(`\bSystem\.out\.print`), 4},
+
+	{"rust", regexp.MustCompile(`(?m)^\s*(pub )?fn \w+(<.*>)?\(`), 3},
+	{"rust", regexp.MustCompile(`\blet mut \w+|\bprintln!\(|\bimpl \w+`), 2},
+
+	{"c", regexp.MustCompile(`(?m)^#include <\w+\.h>`), 4},
+	{"cpp", regexp.MustCompile(`(?m)^#include <\w+>|\bstd::\w+`), 4},
+
+	{"php", regexp.MustCompile(`<\?php`), 6},
+	{"ruby", regexp.MustCompile(`(?m)^\s*(def \w+[?!]?(\(.*\))?|class \w+( < \w+)?|module \w+)\s*$`), 2},
+	{"ruby", regexp.MustCompile(`(?m)^\s*end\s*$|\bputs `), 1},
+	{"dockerfile", regexp.MustCompile(`(?m)^FROM [\w./:-]+`), 3},
+	{"dockerfile", regexp.MustCompile(`(?m)^(RUN|COPY|WORKDIR|ENTRYPOINT|CMD|EXPOSE) `), 2},
+	{"diff", regexp.MustCompile(`(?m)^(@@ -\d+(,\d+)? \+\d+(,\d+)? @@|--- a/|\+\+\+ b/)`), 5},
+}
+
+// GuessCodeBlockLanguage guesses the language of code without a language
+// by looking for patterns that are typical for the most common languages.
+// An empty string is returned if it is not clear enough.
+func GuessCodeBlockLanguage(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" {
+		return ""
+	}
+
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+
+	scores := make(map[string]int)
+	for _, hint := range languageHints {
+		if hint.r.MatchString(content) {
+			scores[hint.language] += hint.score
+		}
+	}
+
+	var best string
+	var bestScore, secondScore int
+	for _, hint := range languageHints {
+		language := hint.language
+		score := scores[language]
+		if language == best {
+			continue
+		}
+		if score > bestScore {
+			best, bestScore, secondScore = language, score, bestScore
+		} else if score > secondScore {
+			secondScore = score
+		}
+	}
+
+	// the language has to be clearly ahead of the others
+	if bestScore < 3 || bestScore-secondScore < 2 {
+		return ""
+	}
+	return best
+}
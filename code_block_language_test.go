@@ -0,0 +1,102 @@
+package md
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestDefaultGetCodeBlockLanguage(t *testing.T) {
+	tests := []struct {
+		name       string
+		html       string
+		expected   string
+		normalized string
+	}{
+		{"prism", `<pre class="language-js"><code class="language-js">x</code></pre>`, "js", "javascript"},
+		{"highlight.js", `<pre><code class="hljs python">x</code></pre>`, "python", "python"},
+		{"highlight.js alias", `<pre><code class="hljs ts">x</code></pre>`, "ts", "typescript"},
+		{"highlight.js only", `<pre><code class="hljs">x</code></pre>`, "", ""},
+		{"rouge", `<div class="language-ruby highlighter-rouge"><div class="highlight"><pre class="highlight"><code>x</code></pre></div></div>`, "ruby", "ruby"},
+		{"pygments", `<div class="highlight-python notranslate"><div class="highlight"><pre>x</pre></div></div>`, "python", "python"},
+		{"github", `<div class="highlight highlight-source-shell"><pre>x</pre></div>`, "shell", "bash"},
+		{"shiki", `<pre class="shiki github-dark" data-language="tsx"><code>x</code></pre>`, "tsx", "tsx"},
+		{"data-lang", `<pre data-lang="golang"><code>x</code></pre>`, "golang", "go"},
+		{"data-lang of a highlighter", `<div class="highlight" data-lang="rust"><pre>x</pre></div>`, "rust", "rust"},
+		{"data-lang of a translation", `<div data-lang="en"><pre><code>x</code></pre></div>`, "", ""},
+		{"syntaxhighlighter", `<pre class="brush: c++; toolbar: false">x</pre>`, "c++", "cpp"},
+		{"nested prefix", `<pre><code class="language-lang-go">x</code></pre>`, "go", "go"},
+		{"plain text", `<div class="language-python"><pre><code class="language-text">x</code></pre></div>`, "text", ""},
+		{"minified classes", `<pre class="mk ml mm"><span>x</span></pre>`, "", ""},
+		{"generated class", `<pre><code class="language-codeBlockLines_39YC">x</code></pre>`, "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(test.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			language := DefaultGetCodeBlockLanguage(doc.Find("pre").First(), "x")
+			if language != test.expected {
+				t.Errorf("expected %q but got %q", test.expected, language)
+			}
+			if normalized := NormalizeCodeBlockLanguage(language); normalized != test.normalized {
+				t.Errorf("expected the normalized %q but got %q", test.normalized, normalized)
+			}
+		})
+	}
+}
+
+func TestGuessCodeBlockLanguage(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected string
+	}{
+		{"package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}", "go"},
+		{"def greet(name):\n    print(f\"hi {name}\")", "python"},
+		{"const x = require('x');\nconsole.log(x);", "javascript"},
+		{"#!/bin/bash\necho hi", "bash"},
+		{`{"name": "x", "version": 1}`, "json"},
+		{"SELECT id, name FROM users WHERE id = 1;", "sql"},
+		{"<?php echo 'hi'; ?>", "php"},
+		{"FROM golang:1.20\nRUN go build", "dockerfile"},
+		{"hello world", ""},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		language := GuessCodeBlockLanguage(test.code)
+		if language != test.expected {
+			t.Errorf("expected %q but got %q for %q", test.expected, language, test.code)
+		}
+	}
+}
+
+func TestGuessCodeBlockLanguageOption(t *testing.T) {
+	input := "<pre><code>package main\n\nfunc main() {}</code></pre>"
+
+	conv := NewConverter("", true, &Options{GuessCodeBlockLanguage: true})
+	markdown, err := conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(markdown, "```go\n") {
+		t.Errorf("expected the language to be guessed but got:\n%s", markdown)
+	}
+
+	conv = NewConverter("", true, &Options{
+		GuessCodeBlockLanguage: true,
+		GetCodeBlockLanguage: func(s *goquery.Selection, content string) string {
+			return "custom"
+		},
+	})
+	markdown, err = conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(markdown, "```custom\n") {
+		t.Errorf("expected the custom language but got:\n%s", markdown)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
-	"unicode/utf8"
 
 	"net/url"
 
@@ -19,6 +18,14 @@ var multipleSpacesR = regexp.MustCompile(`  +`)
 var markdownImageR = regexp.MustCompile(`^!\[([^\]]*)\]\((.+?)(?:\s+"[^"]*")?\)$`)
 var beginningOfLineR = regexp.MustCompile(`(?m)^`)
 
+// listNumberR and splitListMarkerR match an ordered list
+// marker whose number is in another node than the delimiter.
+var listNumberR = regexp.MustCompile(`^\d{1,9}$`)
+var splitListMarkerR = regexp.MustCompile(`^[.)]( |$)`)
+
+// inlineCodeLineBreakR matches a line break in inline code with its surrounding whitespace.
+var inlineCodeLineBreakR = regexp.MustCompile(`[ \t]*\n\s*`)
+
 func (c *Converter) InitializeCommonMarkRules() []Rule {
 
 	return []Rule{
@@ -30,12 +37,15 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				// we have a nested list, were the ul/ol is inside a list item
 				// -> based on work done by @requilence from @anytypeio
 				if (parent.Is("li") || parent.Is("ul") || parent.Is("ol")) && parent.Children().Last().IsSelection(selec) {
-					// add a line break prefix if the parent's text node doesn't have it.
-					// that makes sure that every list item is on its on line
-					lastContentTextNode := strings.TrimRight(parent.Nodes[0].FirstChild.Data, " \t")
-					if !strings.HasSuffix(lastContentTextNode, "\n") {
+					// add a line break prefix if the content before the list doesn't end
+					// with one (like a paragraph). that makes sure that every list item is on its on line
+					if !endsWithLineBreak(previousContent(selec.Get(0))) {
 						content = "\n" + content
 					}
+					// an ordered list that doesn't start with 1 can't interrupt a paragraph
+					if selec.Is("ol") && getListStart(selec) != 1 && previousContent(selec.Get(0)) != nil {
+						content = "\n\n" + strings.TrimLeft(content, "\n")
+					}
 
 					// remove empty lines between lists
 					trimmedSpaceContent := strings.TrimRight(content, " \t")
@@ -79,6 +89,13 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 
 				content = IndentMultiLineListItem(opt, content, prefixCount+previousPrefixCounts)
 
+				// the nested list of a list item without own text follows the previous item,
+				// but an ordered list that doesn't start with 1 can't interrupt its paragraph
+				if nested := selec.ChildrenFiltered("ol").First(); selec.AttrOr(attrListPrefix, "") == "" &&
+					nested.Length() > 0 && getListStart(nested) != 1 && selec.Prev().Length() > 0 {
+					return String("\n" + prefix + content + "\n")
+				}
+
 				return String(prefix + content + "\n")
 			},
 		},
@@ -86,6 +103,15 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 			Filter: []string{"#text"},
 			Replacement: func(content string, selec *goquery.Selection, opt *Options) *string {
 				text := selec.Text()
+				if context, mode := closestPreformatted(selec); mode != "" {
+					if opt.PreformattedStyle == "codeblock" && context.Is(preformattedCodeBlockSelector) {
+						// the text is already part of the code block
+						return String("")
+					}
+					atLineStart := selec.Get(0).PrevSibling == nil && selec.Parent().IsSelection(context)
+					return String(preformattedText(text, mode, atLineStart, opt))
+				}
+
 				if trimmed := strings.TrimSpace(text); trimmed == "" {
 					return String("")
 				}
@@ -95,8 +121,23 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				// normal text be indented and thus be a code block.
 				text = multipleSpacesR.ReplaceAllString(text, " ")
 
+				// the whitespace at the beginning of the new line is not displayed
+				if opt.LineBreakStyle != "paragraph" && isAfterLineBreak(selec.Get(0)) {
+					text = strings.TrimLeftFunc(text, unicode.IsSpace)
+				}
+
 				if opt.EscapeMode == "basic" {
-					text = escape.MarkdownCharacters(text)
+					if isAtLineStart(selec.Get(0)) {
+						text = escape.MarkdownCharacters(text)
+					} else {
+						text = escape.MarkdownCharactersMidLine(text)
+					}
+
+					// the number of an ordered list can be in front of the text,
+					// e.g. "<span>1</span>. Text"
+					if splitListMarkerR.MatchString(text) && listNumberR.MatchString(strings.TrimSpace(lineTextBefore(selec.Get(0)))) {
+						text = `\` + text
+					}
 				}
 
 				// if its inside a list, trim the spaces to not mess up the indentation
@@ -128,6 +169,18 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				return &content
 			},
 		},
+		{
+			Filter: preformattedCodeBlockElements,
+			Replacement: func(content string, selec *goquery.Selection, opt *Options) *string {
+				if opt.PreformattedStyle != "codeblock" {
+					return nil
+				}
+				if _, ok := selec.Attr(attrPreformatted); !ok {
+					return nil
+				}
+				return String(preformattedCodeBlock(selec, opt))
+			},
+		},
 		{
 			Filter: []string{"h1", "h2", "h3", "h4", "h5", "h6"},
 			Replacement: func(content string, selec *goquery.Selection, opt *Options) *string {
@@ -147,11 +200,17 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 					return &text
 				}
 
-				node := goquery.NodeName(selec)
-				level, err := strconv.Atoi(node[1:])
+				level, err := HeadingLevel(selec)
 				if err != nil {
 					return nil
 				}
+				if level > opt.HeadingMaxLevel {
+					text := "\n\n" + opt.StrongDelimiter + content + opt.StrongDelimiter + "\n\n"
+					return &text
+				}
+
+				idPrefix, idSuffix := headingID(selec, opt)
+				content = idPrefix + content + idSuffix
 
 				if opt.HeadingStyle == "setext" && level < 3 {
 					line := "-"
@@ -220,20 +279,45 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 		},
 		{
 			Filter: []string{"img"},
-			Replacement: func(content string, selec *goquery.Selection, opt *Options) *string {
-				src := selec.AttrOr("src", "")
-				src = strings.TrimSpace(src)
+			AdvancedReplacement: func(content string, selec *goquery.Selection, opt *Options) (AdvancedResult, bool) {
+				if opt.ImageStyle == "none" {
+					return AdvancedResult{}, false
+				}
+
+				src := imageSource(selec, opt.ImagePreferredWidth)
 				if src == "" {
-					return String("")
+					return AdvancedResult{}, false
 				}
 
-				src = opt.GetAbsoluteURL(selec, src, opt.domain)
+				src = TransformURL(selec, src, URLKindImage, opt)
+				if src == "" {
+					return AdvancedResult{}, false
+				}
+				if opt.ImageStyle != "alt" {
+					src = AssetURL(selec, src, opt)
+				}
+				src = limitDataURI(src, opt)
+				src = destinationReplacer.Replace(src)
 
 				alt := selec.AttrOr("alt", "")
 				alt = strings.Replace(alt, "\n", " ", -1)
 
+				switch opt.ImageStyle {
+				case "alt":
+					alt = strings.TrimSpace(alt)
+					if opt.EscapeMode == "basic" {
+						alt = escape.MarkdownCharacters(alt)
+					}
+					return AdvancedResult{Markdown: AddSpaceIfNessesary(selec, alt)}, false
+				case "html":
+					return AdvancedResult{Markdown: imageHTML(selec, src, alt)}, false
+				case "referenced":
+					replacement, reference := imageReference(selec, src, alt, opt)
+					return AdvancedResult{Markdown: replacement, Footer: reference}, false
+				}
+
 				text := fmt.Sprintf("![%s](%s)", alt, src)
-				return &text
+				return AdvancedResult{Markdown: text}, false
 			},
 		},
 		{
@@ -246,8 +330,25 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 						Markdown: content,
 					}, false
 				}
+				if opt.LinkStyle == "text" {
+					return AdvancedResult{
+						Markdown: AddSpaceIfNessesary(selec, content),
+					}, false
+				}
 
-				href = opt.GetAbsoluteURL(selec, href, opt.domain)
+				if fragment, ok := fragmentLink(selec, href, opt); ok {
+					href = fragment
+				} else {
+					href = TransformURL(selec, href, URLKindAnchor, opt)
+				}
+				if href == "" {
+					// the url was removed, only the text is kept
+					return AdvancedResult{
+						Markdown: AddSpaceIfNessesary(selec, content),
+					}, false
+				}
+				href = AssetURL(selec, href, opt)
+				href = destinationReplacer.Replace(href)
 
 				// having multiline content inside a link is a bit tricky
 				content = EscapeMultiLine(content)
@@ -282,7 +383,8 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 					}
 				}
 
-				if opt.LinkStyle == "inlined" {
+				isLong := opt.LinkCollapseLength > 0 && len(href) > opt.LinkCollapseLength
+				if opt.LinkStyle == "inlined" && !isLong {
 					md := fmt.Sprintf("[%s](%s%s)", content, href, title)
 					md = AddSpaceIfNessesary(selec, md)
 
@@ -294,7 +396,22 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				var replacement string
 				var reference string
 
-				switch opt.LinkReferenceStyle {
+				linkStyle := opt.LinkReferenceStyle
+				if opt.LinkStyle == "inlined" {
+					// a long url is moved to the end of the document
+					linkStyle = "full"
+				}
+				if opt.LinkStyle == "numbered" {
+					linkStyle = "numbered"
+				}
+
+				// links that share the same number only need one definition
+				id, isNew := opt.references.id(href, title)
+
+				switch linkStyle {
+				case "numbered":
+					replacement = content + " [" + id + "]"
+					reference = "[" + id + "]: " + href + title
 				case "collapsed":
 
 					replacement = "[" + content + "][]"
@@ -304,11 +421,14 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 					reference = "[" + content + "]: " + href + title
 
 				default:
-					id := selec.AttrOr("data-index", "")
 					replacement = "[" + content + "][" + id + "]"
 					reference = "[" + id + "]: " + href + title
 				}
 
+				if (linkStyle == "full" || linkStyle == "numbered") && !isNew {
+					reference = ""
+				}
+
 				replacement = AddSpaceIfNessesary(selec, replacement)
 				return AdvancedResult{Markdown: replacement, Footer: reference}, false
 			},
@@ -319,10 +439,14 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				code := c.inlineCodeContent(selec, opt)
 
 				// Newlines in the text aren't great, since this is inline code and not a code block.
-				// Newlines will be stripped anyway in the browser, but it won't be recognized as code
-				// from the markdown parser when there is more than one newline.
-				// So limit to
-				code = multipleNewLinesRegex.ReplaceAllString(code, "\n")
+				// Newlines will be stripped anyway in the browser, but the markdown parser
+				// could read a line of the code as a block (e.g. a "```" fence or a "# heading").
+				// So replace them (and the indentation around them) with a space like the browser.
+				code = inlineCodeLineBreakR.ReplaceAllString(code, " ")
+				if code == "" {
+					// empty backticks would be displayed as text
+					return String("")
+				}
 
 				fenceChar := '`'
 				maxCount := calculateCodeFenceOccurrences(fenceChar, code)
@@ -330,13 +454,11 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 
 				fence := strings.Repeat(string(fenceChar), maxCount)
 
-				// code block contains a backtick as first character
-				if strings.HasPrefix(code, "`") {
-					code = " " + code
-				}
-				// code block contains a backtick as last character
-				if strings.HasSuffix(code, "`") {
-					code = code + " "
+				// code block contains a backtick as first or last character:
+				// the markdown parser only strips the space if there is one
+				// on both sides, so add one on both sides
+				if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+					code = " " + code + " "
 				}
 
 				// TODO: configure delimeter in options?
@@ -348,19 +470,10 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 		{
 			Filter: []string{"pre"},
 			Replacement: func(content string, selec *goquery.Selection, opt *Options) *string {
-				codeElement := selec.Find("code")
-				language := codeElement.AttrOr("class", "")
-				language = strings.Replace(language, "language-", "", 1)
-
 				code := c.inlineCodeContent(selec, opt)
+				language := CodeBlockLanguage(selec, code, opt)
 
-				fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
-				fence := CalculateCodeFence(fenceChar, code)
-
-				text := "\n\n" + fence + language + "\n" +
-					code +
-					"\n" + fence + "\n\n"
-				return &text
+				return String(codeBlock(code, language, opt))
 			},
 		},
 		{
@@ -379,7 +492,7 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 		{
 			Filter: []string{"br"},
 			Replacement: func(content string, selec *goquery.Selection, opt *Options) *string {
-				return String("\n\n")
+				return String(lineBreak(selec, opt))
 			},
 		},
 		{
@@ -390,11 +503,7 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 					return nil
 				}
 
-				content = multipleNewLinesRegex.ReplaceAllString(content, "\n\n")
-
-				content = beginningOfLineR.ReplaceAllString(content, "> ")
-
-				text := "\n\n" + content + "\n\n"
+				text := "\n\n" + QuoteBlock(content) + "\n\n"
 				return &text
 			},
 		},
@@ -404,6 +513,11 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				// for now remove the contents of noscript. But in the future we could
 				// tell goquery to parse the contents of the tag.
 				// -> https://github.com/PuerkitoBio/goquery/issues/139#issuecomment-517526070
+
+				// the fallback of lazy loaded images is already used by the previous image
+				if selec.Prev().Is("img") && noscriptImage(selec) != nil {
+					return String("")
+				}
 				return nil
 			},
 		},
@@ -439,7 +553,10 @@ func (c *Converter) InitializeCommonMarkRules() []Rule {
 				}
 
 				// For non-data URIs, use the normal URL processing
-				absoluteURL := opt.GetAbsoluteURL(selec, src, opt.domain)
+				absoluteURL := TransformURL(selec, src, URLKindIframe, opt)
+				if absoluteURL == "" {
+					return String("")
+				}
 				return String(fmt.Sprintf("[iframe](%s)", absoluteURL))
 			},
 		},
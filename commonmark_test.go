@@ -26,7 +26,10 @@ type GoldenTest struct {
 	Domain string
 
 	DisableGoldmark bool
-	Variations      map[string]Variation
+	// GoldmarkPerVariation renders a goldmark golden file for every variation,
+	// for the tests where the variations produce different html.
+	GoldmarkPerVariation bool
+	Variations           map[string]Variation
 }
 
 func runGoldenTest(t *testing.T, test GoldenTest, variationKey string) {
@@ -71,6 +74,10 @@ func runGoldenTest(t *testing.T, test GoldenTest, variationKey string) {
 	if !test.DisableGoldmark {
 		// testdata/TestCommonmark/name/goldmark.golden
 		p = path.Join(t.Name(), "goldmark")
+		if test.GoldmarkPerVariation {
+			// testdata/TestCommonmark/name/goldmark.default.golden
+			p += "." + variationKey
+		}
 		g.Assert(t, p, buf.Bytes())
 	}
 }
@@ -242,6 +249,165 @@ func TestCommonmark(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:                 "image_lazy",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"default": {},
+				"preferred_width": {
+					Options: &md.Options{
+						ImagePreferredWidth: 500,
+					},
+				},
+			},
+		},
+		{
+			Name:                 "image_style",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"inlined": {},
+				"referenced": {
+					Options: &md.Options{ImageStyle: "referenced"},
+				},
+				"referenced_collapsed": {
+					Options: &md.Options{ImageStyle: "referenced", LinkReferenceStyle: "collapsed"},
+				},
+				"alt": {
+					Options: &md.Options{ImageStyle: "alt"},
+				},
+				"html": {
+					Options: &md.Options{ImageStyle: "html"},
+				},
+				"none": {
+					Options: &md.Options{ImageStyle: "none"},
+				},
+				"data_uri_limit": {
+					Options: &md.Options{ImageDataURILimit: 50},
+				},
+			},
+		},
+		{
+			Name:                 "link_style",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"inlined": {},
+				"inlined_collapse": {
+					Options: &md.Options{LinkCollapseLength: 50},
+				},
+				"referenced": {
+					Options: &md.Options{LinkStyle: "referenced"},
+				},
+				"numbered": {
+					Options: &md.Options{LinkStyle: "numbered"},
+				},
+				"numbered_hidden": {
+					Options: &md.Options{LinkStyle: "numbered", RemoveHidden: true},
+				},
+				"text": {
+					Options: &md.Options{LinkStyle: "text"},
+				},
+			},
+		},
+		{
+			Name:                 "url_transform",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"default": {},
+				"transformers": {
+					Options: &md.Options{
+						URLTransformers: []md.URLTransformer{
+							md.UnwrapRedirects,
+							md.RemoveTrackingParameters(),
+							md.RewriteURLPrefix("http://example.com/", "https://mirror.example.com/"),
+						},
+					},
+				},
+				"unsafe": {
+					Options: &md.Options{AllowUnsafeURLs: true},
+				},
+			},
+		},
+		{
+			Name:                 "heading_id",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"none": {},
+				"attribute": {
+					Options: &md.Options{HeadingIDStyle: "attribute"},
+				},
+				"anchor": {
+					Options: &md.Options{HeadingIDStyle: "anchor"},
+				},
+				"github": {
+					Options: &md.Options{HeadingIDStyle: "github"},
+				},
+			},
+		},
+		{
+			Name:                 "heading_level",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"default": {},
+				"offset": {
+					Options: &md.Options{HeadingOffset: 1, HeadingMaxLevel: 4},
+				},
+				"renumber": {
+					Options: &md.Options{HeadingRenumber: true},
+				},
+				"single_h1": {
+					Options: &md.Options{HeadingSingleH1: true, HeadingRenumber: true},
+				},
+			},
+		},
+		{
+			Name:                 "hidden",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"default": {},
+				"remove_hidden": {
+					Options: &md.Options{RemoveHidden: true, HiddenClasses: []string{"js-only"}},
+				},
+				"remove_screen_reader_only": {
+					Options: &md.Options{RemoveScreenReaderOnly: true},
+				},
+			},
+		},
+		{
+			Name:                 "preformatted",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"collapse": {},
+				"linebreaks": {
+					Options: &md.Options{PreformattedStyle: "linebreaks"},
+				},
+				"codeblock": {
+					Options: &md.Options{PreformattedStyle: "codeblock"},
+				},
+				"codeblock_indented": {
+					Options: &md.Options{PreformattedStyle: "codeblock", CodeBlockStyle: "indented"},
+				},
+				"codeblock_tilde": {
+					Options: &md.Options{PreformattedStyle: "codeblock", CodeBlockStyle: "fenced", Fence: "~~~"},
+				},
+			},
+		},
+		{
+			Name:                 "line_break",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"paragraph": {},
+				"spaces": {
+					Options: &md.Options{LineBreakStyle: "spaces"},
+				},
+				"backslash": {
+					Options: &md.Options{LineBreakStyle: "backslash"},
+				},
+				"html": {
+					Options: &md.Options{LineBreakStyle: "html"},
+				},
+			},
+		},
+
 		// + all the test on disk that are added automatically
 	}
 
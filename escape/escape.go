@@ -7,14 +7,17 @@ import (
 	"strings"
 )
 
-var backslash = regexp.MustCompile(`\\(\S)`)
-var heading = regexp.MustCompile(`(?m)^(#{1,6} )`)
-var orderedList = regexp.MustCompile(`(?m)^(\W* {0,3})(\d+)\. `)
-var unorderedList = regexp.MustCompile(`(?m)^([^\\\w]*)[*+-] `)
+var heading = regexp.MustCompile(`(?m)^( {0,3})(#{1,6})([ \t]|$)`)
+var orderedList = regexp.MustCompile(`(?m)^(\W* {0,3})(\d+)([.)])([ \t]|$)`)
+var unorderedList = regexp.MustCompile(`(?m)^([^\\\w]*)[*+-]([ \t]|$)`)
 var horizontalDivider = regexp.MustCompile(`(?m)^([-*_] *){3,}$`)
-var blockquote = regexp.MustCompile(`(?m)^(\W* {0,3})> `)
+var setextUnderline = regexp.MustCompile(`(?m)(\n {0,3})(=+|-+)([ \t]*)$`)
+var tildeFence = regexp.MustCompile(`(?m)^([^\w~]* {0,3})(~{3,})`)
+var blockquote = regexp.MustCompile(`(?m)^[^\\\w]*>`)
+var htmlTag = regexp.MustCompile(`<([^\s<>]|$)`)
+var entity = regexp.MustCompile(`&(#?[A-Za-z0-9]+;|$)`)
 var link = regexp.MustCompile(`([\[\]])`)
-var unorderedListBullet = regexp.MustCompile(`([*+-])`)
+var unorderedListBullet = regexp.MustCompile(`([+-])`) // the * is escaped by the replacer
 
 var replacer = strings.NewReplacer(
 	`*`, `\*`,
@@ -27,32 +30,56 @@ var replacer = strings.NewReplacer(
 // `<p>**Not Bold**</p> ends up as correct markdown `\*\*Not Strong\*\*`.
 // No worry, the escaped characters will display fine, just without the formatting.
 func MarkdownCharacters(text string) string {
+	return markdownCharacters(text, true)
+}
+
+// MarkdownCharactersMidLine is like `MarkdownCharacters` for text that does not
+// start a line, for example the text after a `<b>`. The end of the text is not
+// the end of the line, so a marker like "42." at the beginning is kept as it is.
+func MarkdownCharactersMidLine(text string) string {
+	return markdownCharacters(text, false)
+}
+
+func markdownCharacters(text string, atLineStart bool) string {
 	// Escape backslash escapes!
-	text = backslash.ReplaceAllString(text, `\\$1`)
+	text = escapeBackslashes(text)
 
 	// Escape headings
-	text = heading.ReplaceAllString(text, `\$1`)
+	text = replaceMarkers(heading, text, atLineStart, func(t string) string {
+		return heading.ReplaceAllString(t, `$1\$2$3`)
+	})
 
 	// Escape hr
+	// (the * and _ characters are escaped by the replacer below)
 	text = horizontalDivider.ReplaceAllStringFunc(text, func(t string) string {
-		if strings.Contains(t, "-") {
-			return strings.Replace(t, "-", `\-`, 3)
-		} else if strings.Contains(t, "_") {
-			return strings.Replace(t, "_", `\_`, 3)
-		}
-		return strings.Replace(t, "*", `\*`, 3)
+		return strings.Replace(t, "-", `\-`, 3)
 	})
 
+	// Escape setext heading underlines (that are not a hr) below another line
+	text = setextUnderline.ReplaceAllString(text, `$1\$2$3`)
+
+	// Escape fenced code blocks with tildes (backticks are escaped below)
+	text = tildeFence.ReplaceAllString(text, `$1\$2`)
+
 	// Escape ol bullet points
-	text = orderedList.ReplaceAllString(text, `$1$2\. `)
+	text = replaceMarkers(orderedList, text, atLineStart, func(t string) string {
+		return orderedList.ReplaceAllString(t, `$1$2\$3$4`)
+	})
 
 	// Escape ul bullet points
-	text = unorderedList.ReplaceAllStringFunc(text, func(t string) string {
+	text = replaceMarkers(unorderedList, text, atLineStart, func(t string) string {
 		return unorderedListBullet.ReplaceAllString(t, `\$1`)
 	})
 
 	// Escape blockquote indents
-	text = blockquote.ReplaceAllString(text, `$1\> `)
+	text = blockquote.ReplaceAllStringFunc(text, func(t string) string {
+		return strings.Replace(t, ">", `\>`, -1)
+	})
+
+	// Escape html tags, autolinks & entities so that they are not rendered
+	// (also at the end, since the next text could continue them)
+	text = htmlTag.ReplaceAllString(text, `\<$1`)
+	text = entity.ReplaceAllString(text, `\&$1`)
 
 	// Escape em/strong *
 	// Escape em/strong _
@@ -64,3 +91,44 @@ func MarkdownCharacters(text string) string {
 
 	return text
 }
+
+// replaceMarkers replaces the matches of a marker (e.g. "#" or "1.") that is either
+// followed by a space or ends the line. If the text does not start a line, a marker
+// at the end of the first line is kept, since the line continues after the text.
+func replaceMarkers(r *regexp.Regexp, text string, atLineStart bool, replace func(string) string) string {
+	var b strings.Builder
+	var last int
+	for _, loc := range r.FindAllStringSubmatchIndex(text, -1) {
+		// the last group is empty if the marker is not followed by a space
+		endsLine := loc[len(loc)-2] == loc[len(loc)-1]
+		if loc[0] == 0 && !atLineStart && endsLine {
+			continue
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(replace(text[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// escapeBackslashes doubles every backslash that is not followed by
+// a space or tab, so also one at the end of a line (a hard line break)
+// or of the text (the next text could start with a markdown character).
+func escapeBackslashes(text string) string {
+	if !strings.Contains(text, `\`) {
+		return text
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		b.WriteByte(text[i])
+		if text[i] != '\\' {
+			continue
+		}
+		if i+1 == len(text) || (text[i+1] != ' ' && text[i+1] != '\t') {
+			b.WriteByte('\\')
+		}
+	}
+	return b.String()
+}
@@ -24,7 +24,6 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -133,12 +132,24 @@ func validateOptions(opt Options) error {
 	if err := validate(opt.StrongDelimiter, "**", "__"); err != nil {
 		return err
 	}
-	if err := validate(opt.LinkStyle, "inlined", "referenced"); err != nil {
+	if err := validate(opt.LinkStyle, "inlined", "referenced", "numbered", "text"); err != nil {
 		return err
 	}
 	if err := validate(opt.LinkReferenceStyle, "full", "collapsed", "shortcut"); err != nil {
 		return err
 	}
+	if err := validate(opt.HeadingIDStyle, "none", "attribute", "anchor", "github"); err != nil {
+		return err
+	}
+	if err := validate(opt.LineBreakStyle, "paragraph", "spaces", "backslash", "html"); err != nil {
+		return err
+	}
+	if err := validate(opt.PreformattedStyle, "collapse", "linebreaks", "codeblock"); err != nil {
+		return err
+	}
+	if err := validate(opt.ImageStyle, "inlined", "referenced", "alt", "html", "none"); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -164,17 +175,18 @@ func NewConverter(domain string, enableCommonmark bool, options *Options) *Conve
 	}
 
 	conv.before = append(conv.before, func(selec *goquery.Selection) {
-		selec.Find("a[href]").Each(func(i int, s *goquery.Selection) {
-			// TODO: don't hardcode "data-index" and rename it to avoid accidental conflicts
-			s.SetAttr("data-index", strconv.Itoa(i+1))
-		})
+		ariaHeadings(selec)
 	})
 	conv.after = append(conv.after, func(markdown string) string {
 		markdown = strings.TrimSpace(markdown)
 		markdown = multipleNewLinesRegex.ReplaceAllString(markdown, "\n\n")
 
 		// remove unnecessary trailing spaces to have clean markdown
-		markdown = TrimTrailingSpaces(markdown)
+		if conv.snap.Load().options.LineBreakStyle == "spaces" {
+			markdown = TrimTrailingSpacesKeepLineBreaks(markdown)
+		} else {
+			markdown = TrimTrailingSpaces(markdown)
+		}
 
 		return markdown
 	})
@@ -221,6 +233,24 @@ func NewConverter(domain string, enableCommonmark bool, options *Options) *Conve
 	if options.EscapeMode == "" {
 		options.EscapeMode = "basic"
 	}
+	if options.HeadingMaxLevel < 1 || options.HeadingMaxLevel > 6 {
+		options.HeadingMaxLevel = 6
+	}
+	if options.HeadingIDStyle == "" {
+		options.HeadingIDStyle = "none"
+	}
+	if options.LineBreakStyle == "" {
+		options.LineBreakStyle = "paragraph"
+	}
+	if options.PreformattedStyle == "" {
+		options.PreformattedStyle = "collapse"
+	}
+	if options.ImageStyle == "" {
+		options.ImageStyle = "inlined"
+	}
+	if options.ImageDataURIPlaceholder == "" {
+		options.ImageDataURIPlaceholder = "data:,"
+	}
 
 	// for now, store it in the options
 	options.domain = domain
@@ -431,7 +461,18 @@ func (conv *Converter) Convert(selec *goquery.Selection) string {
 
 	// Precompute list indentation metadata *after* before hooks (so any DOM mutations
 	// performed by user hooks are reflected).
+	removeHiddenContent(selec, &options)
+	removeImages(selec, &options)
+	annotatePreformatted(selec, &options)
 	annotateListIndentation(selec, &options)
+	annotateHeadingLevels(selec, &options)
+	// after the before hooks, so that removed headings
+	// (e.g. of a table of contents) don't get a slug
+	annotateHeadingIDs(selec)
+
+	options.references = newLinkReferences()
+	options.imageReferences = newLinkReferences()
+	options.assets = prefetchAssets(selec, &options)
 
 	res := conv.selecToMD(selec, &options)
 	markdown := res.Markdown
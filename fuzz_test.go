@@ -0,0 +1,319 @@
+package md
+
+import (
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/firecrawl/html-to-markdown/escape"
+	nethtml "golang.org/x/net/html"
+)
+
+// The fuzz targets can be run with for example `go test -fuzz=FuzzConvertString`.
+// Without the -fuzz flag only the seed corpus is checked.
+
+// addSeedCorpus adds the input.html files from the testdata folder to the seed corpus.
+func addSeedCorpus(f *testing.F) {
+	files, err := filepath.Glob(filepath.Join("testdata", "Test*", "*", "input.html"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(string(data))
+	}
+}
+
+var blankLinesR = regexp.MustCompile(`\n\s*\n`)
+
+// roundTrip renders the markdown to html and converts it back to markdown.
+func roundTrip(t *testing.T, conv *Converter, markdown string) string {
+	rendered, err := renderMarkdown(markdown, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markdown, err = conv.ConvertString(rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return markdown
+}
+
+// renderNormalized renders the markdown and normalizes the whitespace between lines.
+func renderNormalized(t *testing.T, markdown string) string {
+	rendered, err := renderMarkdown(markdown, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blankLinesR.ReplaceAllString(rendered, "\n")
+}
+
+func FuzzConvertString(f *testing.F) {
+	addSeedCorpus(f)
+	f.Add(`<p>*not bold* and <b>bold</b></p>`)
+	f.Add(`<ol start="9"><li>one<ul><li>two</li></ul></li></ol>`)
+	f.Add("<pre><code>```\ncode\n```</code></pre>")
+
+	conv := NewConverter("", true, nil)
+	f.Fuzz(func(t *testing.T, input string) {
+		markdown, err := conv.ConvertString(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Converting goldmark's html of the output again is a fixed point.
+		// The first round trip is allowed to normalize the markdown, after
+		// that the rendered html must not change (except for whitespace).
+		second := roundTrip(t, conv, markdown)
+		third := roundTrip(t, conv, second)
+		if renderNormalized(t, second) != renderNormalized(t, third) {
+			t.Errorf("not a fixed point:\n%q\n%q", second, third)
+		}
+
+		// The text that goldmark renders from the output is the text that the
+		// browser displays for the input. The whitespace is ignored, since
+		// e.g. the spaces around block elements are not preserved.
+		if !utf8.ValidString(input) {
+			return
+		}
+		original, ok := displayedText(t, input)
+		if !ok {
+			return
+		}
+		rendered, err := renderMarkdown(markdown, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		converted, _ := displayedText(t, rendered)
+		if original != converted {
+			t.Errorf("the text changed:\nmarkdown: %q\nexpected: %q\nactual:   %q", markdown, original, converted)
+		}
+	})
+}
+
+// textChangingElements are converted to a different text than the browser
+// displays on purpose, for example the `<title>`, the url of an `<iframe>`,
+// the `title` of a link without text or the markdown of an image in a code block.
+var textChangingElements = strings.Join([]string{
+	"title", "iframe", "noscript", "math", "svg", "object", "select", "textarea",
+	"a[title]:empty", "a[aria-label]:empty", "a[title]:has(img, svg)",
+	"pre a", "pre img", "code a", "code img",
+}, ", ")
+
+// displayedText returns the text that a browser displays, without whitespace.
+// It is false if the html contains one of the `textChangingElements`.
+func displayedText(t *testing.T, rawHTML string) (string, bool) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Find(textChangingElements).Length() > 0 {
+		return "", false
+	}
+	// block elements inside of inline elements or headings
+	// (e.g. `<b><h1>` or `<h1><ol>`), text that is not in a list
+	// item of a list (e.g. `<ol>text<li>`) and list items outside
+	// of a list are not supported
+	var unsupported bool
+	doc.Find("*").Each(func(i int, s *goquery.Selection) {
+		parent := s.Parent()
+		if !IsInlineElement(goquery.NodeName(s)) && (IsInlineElement(goquery.NodeName(parent)) || parent.Is(headingSelector)) {
+			unsupported = true
+		}
+	})
+	if doc.Find("li").Not("ul > li, ol > li").Length() > 0 {
+		unsupported = true
+	}
+	doc.Find("ul, ol").Contents().Each(func(i int, s *goquery.Selection) {
+		if !s.Is("li, ul, ol") && strings.TrimSpace(s.Text()) != "" {
+			unsupported = true
+		}
+	})
+	if unsupported {
+		return "", false
+	}
+	doc.Find("script, style").Remove()
+	return strings.Join(strings.Fields(doc.Text()), ""), true
+}
+
+// visibleText returns the text that a browser displays, with normalized whitespace.
+func visibleText(t *testing.T, rawHTML string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(rawHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+func FuzzMarkdownCharacters(f *testing.F) {
+	for _, seed := range []string{
+		"hello *world*", "1. not a list", "1) not a list", "# not a heading",
+		"a_b_c", "<b>not bold</b>", "&amp; &#35;", "[not](a link)", "![not an image]",
+		"`not code`", "+ - * not a list", "---", "> not a quote", "not\n===", "~~~",
+		`\* backslash`, "| not | a | table |", "~~~~~~",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		// text that can't appear in a html text node
+		if !utf8.ValidString(text) || strings.ContainsAny(text, "\x00\r") {
+			t.Skip()
+		}
+		// like in the text rule, the whitespace is collapsed before escaping
+		// (otherwise an indentation would be a code block)
+		text = multipleSpacesR.ReplaceAllString(tabR.ReplaceAllString(text, " "), " ")
+
+		markdown := escape.MarkdownCharacters(text)
+		rendered, err := renderMarkdown(markdown, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the visible text is preserved after unescaping
+		expected := strings.Join(strings.Fields(text), " ")
+		if actual := visibleText(t, rendered); actual != expected {
+			t.Errorf("the text changed for %q:\nmarkdown: %q\nexpected: %q\nactual:   %q", text, markdown, expected, actual)
+		}
+	})
+}
+
+func FuzzCodeBlock(f *testing.F) {
+	f.Add("code", false)
+	f.Add("```\ncode\n````", false)
+	f.Add("~~~ tilde\n~~~~~", true)
+	f.Add("`", false)
+	f.Add("\n\nleading and trailing\n\n", true)
+
+	f.Fuzz(func(t *testing.T, code string, tilde bool) {
+		if !utf8.ValidString(code) || strings.ContainsAny(code, "\x00\r") {
+			t.Skip()
+		}
+
+		fence := "```"
+		if tilde {
+			fence = "~~~"
+		}
+		conv := NewConverter("", true, &Options{CodeBlockStyle: "fenced", Fence: fence})
+
+		input := "<pre><code>" + html.EscapeString(code) + "</code></pre><p>after</p>"
+		markdown, err := conv.ConvertString(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rendered, err := renderMarkdown(markdown, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(rendered))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the code fence always closes, so the paragraph is outside of the code block
+		if doc.Find("pre").Length() != 1 || doc.Find("p").Text() != "after" {
+			t.Fatalf("the code fence did not close:\n%s", markdown)
+		}
+
+		// the after hooks reduce newlines and remove trailing spaces, also inside of code blocks
+		expected := TrimTrailingSpaces(multipleNewLinesRegex.ReplaceAllString(code, "\n\n"))
+		actual := strings.TrimSuffix(doc.Find("pre code").Text(), "\n")
+		if strings.TrimSpace(expected) != strings.TrimSpace(actual) {
+			t.Errorf("the code changed:\nexpected: %q\nactual:   %q", expected, actual)
+		}
+	})
+}
+
+func FuzzCalculateCodeFence(f *testing.F) {
+	f.Add("code", false)
+	f.Add("```````", false)
+	f.Add("~~ ~~~~ ~", true)
+
+	f.Fuzz(func(t *testing.T, content string, tilde bool) {
+		fenceChar := '`'
+		if tilde {
+			fenceChar = '~'
+		}
+
+		fence := CalculateCodeFence(fenceChar, content)
+		if len(fence) < 3 || strings.Trim(fence, string(fenceChar)) != "" {
+			t.Fatalf("invalid fence %q", fence)
+		}
+		if strings.Contains(content, fence) {
+			t.Errorf("the fence %q also appears in the content %q", fence, content)
+		}
+	})
+}
+
+func FuzzTrimpLeadingSpaces(f *testing.F) {
+	f.Add("  text\n    code\n- list")
+	f.Add("```\n  inside\n```\n  outside")
+	f.Add("\t\ttabs")
+
+	f.Fuzz(func(t *testing.T, text string) {
+		// the text is handled as runes, so invalid utf-8 is replaced
+		if !utf8.ValidString(text) {
+			t.Skip()
+		}
+
+		lines := strings.Split(text, "\n")
+		trimmed := strings.Split(TrimpLeadingSpaces(text), "\n")
+		if len(lines) != len(trimmed) {
+			t.Fatalf("expected %d lines but got %d", len(lines), len(trimmed))
+		}
+
+		// only leading whitespace is removed
+		for i := range lines {
+			if !strings.HasSuffix(lines[i], trimmed[i]) {
+				t.Fatalf("line %q is not a suffix of %q", trimmed[i], lines[i])
+			}
+			removed := lines[i][:len(lines[i])-len(trimmed[i])]
+			if strings.TrimSpace(removed) != "" {
+				t.Errorf("removed %q from line %q", removed, lines[i])
+			}
+		}
+	})
+}
+
+func FuzzAnnotateListIndentation(f *testing.F) {
+	addSeedCorpus(f)
+	f.Add(`<ol start="-3"><li>a</li><p>b</p><li>c<ol start="99"><li>d</li></ol></li></ol><li>outside</li>`)
+
+	opt := &Options{BulletListMarker: "-"}
+	f.Fuzz(func(t *testing.T, input string) {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		annotateListIndentation(doc.Selection, opt)
+
+		// every list item has the metadata that is needed by the `li` rule
+		doc.Find("li").Each(func(i int, s *goquery.Selection) {
+			for _, attr := range []string{attrListPrefixCount, attrListPrevPrefixCounts} {
+				count, err := strconv.Atoi(s.AttrOr(attr, ""))
+				if err != nil || count < 0 {
+					t.Fatalf("invalid %s for %s", attr, nodeString(s))
+				}
+			}
+			if _, ok := s.Attr(attrListPrefix); !ok {
+				t.Fatalf("missing %s for %s", attrListPrefix, nodeString(s))
+			}
+		})
+	})
+}
+
+func nodeString(s *goquery.Selection) string {
+	var b strings.Builder
+	_ = nethtml.Render(&b, s.Get(0))
+	return b.String()
+}
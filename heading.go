@@ -0,0 +1,269 @@
+package md
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	// The id that is emitted for the heading, links to one of
+	// the other ids inside of the heading are rewritten to it.
+	attrHeadingID = "data-converter-heading-id"
+	// The slug that GitHub generates for the heading.
+	attrHeadingSlug = "data-converter-heading-slug"
+
+	// The level of the heading after the `HeadingOffset`, `HeadingRenumber`, ...
+	attrHeadingLevel = "data-converter-heading-level"
+
+	// The id and slug of the heading that a fragment link ("#installation") points to.
+	attrFragmentID   = "data-converter-fragment-id"
+	attrFragmentSlug = "data-converter-fragment-slug"
+)
+
+// attributeIDR matches the ids that can be written into the `{#id}` attribute.
+var attributeIDR = regexp.MustCompile(`^[\p{L}\p{N}_][\p{L}\p{N}_.:-]*$`)
+
+// headingSelector matches all the headings.
+const headingSelector = "h1, h2, h3, h4, h5, h6"
+
+// GitHubSlug returns the id that GitHub generates for a heading with that text.
+// Duplicate slugs are not handled here, GitHub appends "-1", "-2", ... to them.
+func GitHubSlug(text string) string {
+	text = strings.ToLower(strings.Join(strings.Fields(text), " "))
+
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ariaHeadings turns the elements with `role="heading"` into real headings.
+// Without an `aria-level` the level is 2, like browsers do it.
+func ariaHeadings(selec *goquery.Selection) {
+	selec.Find("[role='heading']").Each(func(i int, s *goquery.Selection) {
+		if s.Is(headingSelector) {
+			return
+		}
+		level, err := strconv.Atoi(strings.TrimSpace(s.AttrOr("aria-level", "")))
+		if err != nil || level < 1 {
+			level = 2
+		}
+		if level > 6 {
+			level = 6
+		}
+
+		n := s.Get(0)
+		n.Data = "h" + strconv.Itoa(level)
+		n.DataAtom = atom.Lookup([]byte(n.Data))
+	})
+}
+
+// annotateHeadingLevels stores the level of every heading after applying
+// the heading options (`HeadingSingleH1`, `HeadingRenumber` and `HeadingOffset`).
+func annotateHeadingLevels(selec *goquery.Selection, opt *Options) {
+	if !opt.HeadingSingleH1 && !opt.HeadingRenumber && opt.HeadingOffset == 0 {
+		return
+	}
+
+	type parent struct {
+		original int
+		level    int
+	}
+	var parents []parent
+	var hasH1, demote bool
+
+	selec.Find(headingSelector).Each(func(i int, s *goquery.Selection) {
+		level, _ := strconv.Atoi(goquery.NodeName(s)[1:])
+
+		if opt.HeadingSingleH1 {
+			// from the second h1 on, the sections are demoted
+			// so that the headings inside stay below them
+			if level == 1 && hasH1 {
+				demote = true
+			}
+			if level == 1 {
+				hasH1 = true
+			}
+			if demote {
+				level++
+			}
+		}
+
+		if opt.HeadingRenumber {
+			// the level is one deeper than the closest heading with a lower level
+			for len(parents) > 0 && parents[len(parents)-1].original >= level {
+				parents = parents[:len(parents)-1]
+			}
+			renumbered := 1
+			if len(parents) > 0 {
+				renumbered = parents[len(parents)-1].level + 1
+			}
+			parents = append(parents, parent{original: level, level: renumbered})
+			level = renumbered
+		}
+
+		level += opt.HeadingOffset
+		if level < 1 {
+			level = 1
+		}
+		s.SetAttr(attrHeadingLevel, strconv.Itoa(level))
+	})
+}
+
+// HeadingLevel returns the level of the heading after the `HeadingOffset`,
+// `HeadingRenumber`, ... which can be deeper than 6.
+func HeadingLevel(selec *goquery.Selection) (int, error) {
+	if level, ok := selec.Attr(attrHeadingLevel); ok {
+		return strconv.Atoi(level)
+	}
+	return strconv.Atoi(goquery.NodeName(selec)[1:])
+}
+
+// annotateHeadingIDs stores the id and the slug of every heading and
+// marks the fragment links that point to one of the headings.
+func annotateHeadingIDs(selec *goquery.Selection) {
+	slugs := make(map[string]int)
+	targets := make(map[string]*goquery.Selection)
+
+	selec.Find(headingSelector).Each(func(i int, s *goquery.Selection) {
+		// duplicates get a number, the same way as GitHub does it
+		slug := GitHubSlug(s.Text())
+		original := slug
+		for {
+			if _, ok := slugs[slug]; !ok {
+				break
+			}
+			slugs[original]++
+			slug = original + "-" + strconv.Itoa(slugs[original])
+		}
+		slugs[slug] = 0
+		s.SetAttr(attrHeadingSlug, slug)
+
+		// the id can also be on an anchor inside of the heading,
+		// for example `<h2><a name="install"></a>Install</h2>`
+		var ids []string
+		if id := strings.TrimSpace(s.AttrOr("id", "")); id != "" {
+			ids = append(ids, id)
+		}
+		s.Find("[id], a[name]").Each(func(i int, inner *goquery.Selection) {
+			id := strings.TrimSpace(inner.AttrOr("id", inner.AttrOr("name", "")))
+			if id != "" {
+				ids = append(ids, id)
+			}
+		})
+		if len(ids) == 0 {
+			return
+		}
+
+		s.SetAttr(attrHeadingID, ids[0])
+		for _, id := range ids {
+			if _, ok := targets[id]; !ok {
+				targets[id] = s
+			}
+		}
+	})
+	if len(targets) == 0 {
+		return
+	}
+
+	selec.Find("a[href^='#']").Each(func(i int, s *goquery.Selection) {
+		id := strings.TrimPrefix(s.AttrOr("href", ""), "#")
+		heading, ok := targets[id]
+		if !ok {
+			return
+		}
+		s.SetAttr(attrFragmentID, heading.AttrOr(attrHeadingID, ""))
+		s.SetAttr(attrFragmentSlug, heading.AttrOr(attrHeadingSlug, ""))
+	})
+}
+
+// HeadingAnchor returns the anchor that a link to the heading uses: the
+// id that is kept with the `HeadingIDStyle` or else the slug that GitHub generates.
+func HeadingAnchor(selec *goquery.Selection, opt *Options) string {
+	id := selec.AttrOr(attrHeadingID, "")
+	switch opt.HeadingIDStyle {
+	case "attribute":
+		id = attributeID(id)
+	case "anchor":
+		id = fragmentReplacer.Replace(id)
+	default:
+		id = ""
+	}
+	if id != "" {
+		return id
+	}
+	return selec.AttrOr(attrHeadingSlug, "")
+}
+
+// attributeID returns the id for the `{#id}` attribute. Spaces and
+// braces would break the syntax, so these ids are replaced with their slug.
+func attributeID(id string) string {
+	if id == "" || attributeIDR.MatchString(id) {
+		return id
+	}
+	return GitHubSlug(id)
+}
+
+// headingID returns the markdown for the id of the heading, depending on the `HeadingIDStyle`.
+func headingID(selec *goquery.Selection, opt *Options) (prefix string, suffix string) {
+	id := selec.AttrOr(attrHeadingID, "")
+	if id == "" {
+		return "", ""
+	}
+
+	switch opt.HeadingIDStyle {
+	case "attribute":
+		if id = attributeID(id); id == "" {
+			return "", ""
+		}
+		return "", " {#" + id + "}"
+	case "anchor":
+		return `<a id="` + strings.ReplaceAll(id, `"`, "&quot;") + `"></a>`, ""
+	}
+	return "", ""
+}
+
+// fragmentLink returns the in-page link ("#installation") for links
+// to a heading, depending on the `HeadingIDStyle`.
+func fragmentLink(selec *goquery.Selection, href string, opt *Options) (string, bool) {
+	if opt.HeadingIDStyle == "none" || !strings.HasPrefix(href, "#") {
+		return "", false
+	}
+
+	switch opt.HeadingIDStyle {
+	case "github":
+		if slug, ok := selec.Attr(attrFragmentSlug); ok {
+			return "#" + slug, true
+		}
+	case "attribute":
+		if id, ok := selec.Attr(attrFragmentID); ok {
+			if id = attributeID(id); id == "" {
+				id = selec.AttrOr(attrFragmentSlug, "")
+			}
+			return "#" + id, true
+		}
+	default:
+		if id, ok := selec.Attr(attrFragmentID); ok {
+			return "#" + fragmentReplacer.Replace(id), true
+		}
+	}
+	// a link to an element that is not a heading
+	return fragmentReplacer.Replace(href), true
+}
+
+// fragmentReplacer escapes the characters of an id that would end the
+// destination of the markdown link.
+var fragmentReplacer = strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E")
@@ -0,0 +1,23 @@
+package md
+
+import "testing"
+
+func TestGitHubSlug(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"Getting Started", "getting-started"},
+		{"Installation & Setup", "installation--setup"},
+		{"What's new in v2.0?", "whats-new-in-v20"},
+		{"  snake_case and kebab-case  ", "snake_case-and-kebab-case"},
+		{"Über die Größe", "über-die-größe"},
+		{"日本語 の 見出し", "日本語-の-見出し"},
+		{"C++ / C#", "c--c"},
+	}
+	for _, test := range tests {
+		if actual := GitHubSlug(test.text); actual != test.expected {
+			t.Errorf("GitHubSlug(%q): expected %q but got %q", test.text, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package md
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultHiddenClasses are the utility classes of Bootstrap, Tailwind,
+// Bulma and Foundation that hide an element.
+var defaultHiddenClasses = []string{
+	"hidden", "d-none", "is-hidden", "hide", "display-none", "invisible",
+}
+
+// screenReaderClasses are the classes that hide the text visually,
+// but keep it for screen readers.
+var screenReaderClasses = []string{
+	"sr-only", "visually-hidden", "visuallyhidden", "screen-reader-text",
+	"screen-reader-only", "show-for-sr", "a11y-hidden", "u-visually-hidden",
+}
+
+// responsiveDisplayR matches the classes that show an element again on larger
+// screens (e.g. "hidden md:block" or "d-none d-md-flex").
+var responsiveDisplayR = regexp.MustCompile(`^(?:(?:sm|md|lg|xl|2xl):|d-(?:sm|md|lg|xl|xxl)-)(?:inline-)?(?:block|flex|inline|grid|table)$`)
+
+var hiddenStyleR = regexp.MustCompile(`(?i)(?:^|;)\s*(?:display\s*:\s*none|visibility\s*:\s*hidden)\s*(?:!important)?\s*(?:;|$)`)
+
+// removeHiddenContent removes the elements that are not visible in the
+// browser (`RemoveHidden`) and the screen reader only text (`RemoveScreenReaderOnly`).
+func removeHiddenContent(selec *goquery.Selection, opt *Options) {
+	if opt.RemoveHidden {
+		classes := make(map[string]bool)
+		for _, class := range append(defaultHiddenClasses, opt.HiddenClasses...) {
+			classes[class] = true
+		}
+
+		selec.Find("template, [hidden], [aria-hidden], [style], [class]").Each(func(i int, s *goquery.Selection) {
+			if isHidden(s, classes) {
+				s.Remove()
+			}
+		})
+	}
+
+	if opt.RemoveScreenReaderOnly {
+		selector := "." + strings.Join(screenReaderClasses, ", .")
+		selec.Find(selector).Each(func(i int, s *goquery.Selection) {
+			// the text is kept if it is the only label of an icon link or button
+			label := s.Closest("a, button")
+			if label.Length() > 0 && strings.TrimSpace(label.Text()) == strings.TrimSpace(s.Text()) {
+				return
+			}
+			s.Remove()
+		})
+	}
+}
+
+func isHidden(s *goquery.Selection, classes map[string]bool) bool {
+	if s.Is("template") {
+		return true
+	}
+	if _, ok := s.Attr("hidden"); ok {
+		return true
+	}
+	if strings.EqualFold(strings.TrimSpace(s.AttrOr("aria-hidden", "")), "true") {
+		return true
+	}
+	if hiddenStyleR.MatchString(s.AttrOr("style", "")) {
+		return true
+	}
+
+	var hidden bool
+	for _, class := range strings.Fields(s.AttrOr("class", "")) {
+		if responsiveDisplayR.MatchString(class) {
+			// the element is only hidden on small screens
+			return false
+		}
+		if classes[class] {
+			hidden = true
+		}
+	}
+	return hidden
+}
@@ -0,0 +1,267 @@
+package md
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// lazyImageAttributes are used by lazy loading libraries for the real url of the image.
+var lazyImageAttributes = []string{
+	"data-src", "data-lazy-src", "data-original", "data-lazy", "data-url",
+	"data-echo", "data-hi-res-src", "data-full-src",
+}
+
+var srcsetAttributes = []string{"srcset", "data-srcset", "data-lazy-srcset"}
+
+// imagePlaceholderR matches the file names of images that are
+// displayed until the real image is loaded. Only the whole file
+// name is matched, so that e.g. "gray-wolf.jpg" is a real image.
+var imagePlaceholderR = regexp.MustCompile(`(?i)(^|/)(blank|spacer|pixel|transparent|empty|1x1|grey|gray|loading|lazy|lazyload|placeholder)\.(gif|png|svg|jpe?g|webp)([?#].*)?$`)
+
+type srcsetCandidate struct {
+	url     string
+	width   int
+	density float64
+}
+
+// parseSrcset parses the candidates of a `srcset` like "image-480.jpg 480w, image-800.jpg 800w".
+func parseSrcset(srcset string) []srcsetCandidate {
+	var candidates []srcsetCandidate
+
+	rest := srcset
+	for {
+		rest = strings.TrimLeftFunc(rest, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ','
+		})
+		if rest == "" {
+			return candidates
+		}
+
+		// the url can contain commas, it ends at the first whitespace
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end == -1 {
+			end = len(rest)
+		}
+		rawURL := rest[:end]
+		rest = rest[end:]
+
+		var descriptors string
+		if strings.HasSuffix(rawURL, ",") {
+			rawURL = strings.TrimRight(rawURL, ",")
+		} else if end := strings.IndexByte(rest, ','); end != -1 {
+			descriptors, rest = rest[:end], rest[end+1:]
+		} else {
+			descriptors, rest = rest, ""
+		}
+
+		candidate := srcsetCandidate{url: rawURL, density: 1}
+		for _, descriptor := range strings.Fields(descriptors) {
+			value := descriptor[:len(descriptor)-1]
+			switch descriptor[len(descriptor)-1] {
+			case 'w':
+				candidate.width, _ = strconv.Atoi(value)
+			case 'x':
+				candidate.density, _ = strconv.ParseFloat(value, 64)
+			}
+		}
+		candidates = append(candidates, candidate)
+	}
+}
+
+// bestSrcsetCandidate returns the smallest candidate that is at least as wide
+// as the preferred width. Without a preferred width the largest candidate is used.
+func bestSrcsetCandidate(candidates []srcsetCandidate, preferredWidth int, imgWidth int) string {
+	size := func(c srcsetCandidate) float64 {
+		if c.width > 0 {
+			return float64(c.width)
+		}
+		if imgWidth > 0 {
+			return c.density * float64(imgWidth)
+		}
+		return c.density
+	}
+
+	var best, largest *srcsetCandidate
+	for i := range candidates {
+		c := &candidates[i]
+		if isImagePlaceholder(c.url) {
+			continue
+		}
+		if largest == nil || size(*c) > size(*largest) {
+			largest = c
+		}
+		if preferredWidth > 0 && size(*c) >= float64(preferredWidth) && (best == nil || size(*c) < size(*best)) {
+			best = c
+		}
+	}
+
+	if best != nil {
+		return best.url
+	}
+	if largest != nil {
+		return largest.url
+	}
+	return ""
+}
+
+// isImagePlaceholder reports whether the url is a known placeholder image.
+func isImagePlaceholder(rawURL string) bool {
+	return imagePlaceholderR.MatchString(rawURL)
+}
+
+// imageSource returns the url of the image. Lazy loading libraries and responsive
+// images place the url into other attributes than `src`, so it is chosen from
+// (in that order) the srcset of the image and a surrounding `<picture>`, the
+// attributes of lazy loading libraries, the `src` and an image inside a `<noscript>`.
+// A placeholder `src` is only skipped if there is one of the alternatives.
+func imageSource(selec *goquery.Selection, preferredWidth int) string {
+	imgWidth, _ := strconv.Atoi(selec.AttrOr("width", ""))
+
+	var candidates []srcsetCandidate
+	addSrcset := func(s *goquery.Selection) {
+		for _, attr := range srcsetAttributes {
+			candidates = append(candidates, parseSrcset(s.AttrOr(attr, ""))...)
+		}
+	}
+	if parent := selec.Parent(); parent.Is("picture") {
+		parent.ChildrenFiltered("source").Each(func(i int, source *goquery.Selection) {
+			addSrcset(source)
+		})
+	}
+	addSrcset(selec)
+
+	if src := bestSrcsetCandidate(candidates, preferredWidth, imgWidth); src != "" {
+		return src
+	}
+
+	for _, attr := range lazyImageAttributes {
+		src := strings.TrimSpace(selec.AttrOr(attr, ""))
+		if src != "" && !isImagePlaceholder(src) {
+			return src
+		}
+	}
+
+	src := strings.TrimSpace(selec.AttrOr("src", ""))
+	isDataURI := strings.HasPrefix(strings.ToLower(src), "data:")
+	if src != "" && !isDataURI && !isImagePlaceholder(src) {
+		return src
+	}
+
+	if img := noscriptImage(selec.Next()); img != nil {
+		fallback := imageSource(goquery.NewDocumentFromNode(img).Selection, preferredWidth)
+		if fallback != "" {
+			return fallback
+		}
+	}
+
+	// without an alternative, the data uri or placeholder
+	// could also be the real image
+	return src
+}
+
+// noscriptImage returns the `<img>` inside of the `<noscript>`, that lazy loading
+// libraries add as a fallback. The content of a noscript is parsed as text.
+func noscriptImage(s *goquery.Selection) *html.Node {
+	if !s.Is("noscript") {
+		return nil
+	}
+
+	nodes, err := html.ParseFragment(strings.NewReader(s.Text()), &html.Node{
+		Type:     html.ElementNode,
+		Data:     "div",
+		DataAtom: atom.Div,
+	})
+	if err != nil {
+		return nil
+	}
+
+	for _, n := range nodes {
+		doc := goquery.NewDocumentFromNode(n)
+		if doc.Is("img") {
+			return n
+		}
+		if img := doc.Find("img"); img.Length() > 0 {
+			return img.Get(0)
+		}
+	}
+	return nil
+}
+
+// limitDataURI replaces a data uri that is longer than the `ImageDataURILimit`.
+func limitDataURI(src string, opt *Options) string {
+	if opt.ImageDataURILimit <= 0 || len(src) <= opt.ImageDataURILimit {
+		return src
+	}
+	if !strings.HasPrefix(strings.ToLower(src), "data:") {
+		return src
+	}
+	return opt.ImageDataURIPlaceholder
+}
+
+// imageHTML returns an `<img>` tag that keeps the size of the image.
+func imageHTML(selec *goquery.Selection, src, alt string) string {
+	var b strings.Builder
+	b.WriteString(`<img src="` + html.EscapeString(src) + `"`)
+	b.WriteString(` alt="` + html.EscapeString(alt) + `"`)
+	for _, key := range []string{"title", "width", "height"} {
+		if val, ok := selec.Attr(key); ok {
+			b.WriteString(" " + key + `="` + html.EscapeString(val) + `"`)
+		}
+	}
+	b.WriteString(" />")
+	return b.String()
+}
+
+// imageReference returns the reference-style image and its
+// definition, like the links it uses the `LinkReferenceStyle`.
+// The definition is empty if the image shares it with an earlier one.
+func imageReference(selec *goquery.Selection, src, alt string, opt *Options) (string, string) {
+	var replacement, reference string
+
+	switch {
+	case opt.LinkReferenceStyle == "collapsed" && alt != "":
+		replacement = "![" + alt + "][]"
+		reference = "[" + alt + "]: " + src
+	case opt.LinkReferenceStyle == "shortcut" && alt != "":
+		replacement = "![" + alt + "]"
+		reference = "[" + alt + "]: " + src
+	default:
+		// the images are numbered separately from the links
+		number, isNew := opt.imageReferences.id(src, "")
+		id := "image" + number
+		replacement = "![" + alt + "][" + id + "]"
+		if isNew {
+			reference = "[" + id + "]: " + src
+		}
+	}
+
+	return replacement, reference
+}
+
+// removeImages removes the images for the `ImageStyle` "none". The space
+// next to an image inside of the text is removed as well, so that
+// "A <img> and" doesn't become "A  and".
+func removeImages(selec *goquery.Selection, opt *Options) {
+	if opt.ImageStyle != "none" {
+		return
+	}
+
+	selec.Find("img").Each(func(i int, s *goquery.Selection) {
+		img := s.Get(0)
+		prev, next := img.PrevSibling, img.NextSibling
+		s.Remove()
+
+		if prev == nil || next == nil || prev.Type != html.TextNode || next.Type != html.TextNode {
+			return
+		}
+		if strings.TrimRightFunc(prev.Data, unicode.IsSpace) != prev.Data {
+			next.Data = strings.TrimLeftFunc(next.Data, unicode.IsSpace)
+		}
+	})
+}
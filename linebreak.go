@@ -0,0 +1,158 @@
+package md
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// HardLineBreak returns the markdown of a line break inside of a
+// paragraph, depending on the `LineBreakStyle`.
+func HardLineBreak(opt *Options) string {
+	switch opt.LineBreakStyle {
+	case "spaces":
+		return "  \n"
+	case "html":
+		return "<br>\n"
+	}
+	return "\\\n"
+}
+
+// lineBreak returns the markdown for a `<br>` depending on the `LineBreakStyle`
+// and the context, since a line break can't be used everywhere.
+func lineBreak(selec *goquery.Selection, opt *Options) string {
+	if opt.LineBreakStyle == "paragraph" {
+		return "\n\n"
+	}
+
+	switch {
+	case selec.Closest("td, th").Length() > 0:
+		// the table turns the new lines into `<br>`
+		return "\n"
+	case selec.Closest("h1, h2, h3, h4, h5, h6").Length() > 0:
+		// a heading has to be on one line
+		return " "
+	case selec.Closest("a").Length() > 0:
+		// the new lines in a link are escaped with a backslash
+		if opt.LineBreakStyle == "html" {
+			return "<br>"
+		}
+		return "\n"
+	case isTrailingBreak(selec.Get(0)):
+		// a hard line break at the end of a paragraph is displayed as text
+		return "\n"
+	}
+	return HardLineBreak(opt)
+}
+
+// isTrailingBreak reports whether there is no more content after the `<br>`.
+func isTrailingBreak(n *html.Node) bool {
+	for next := n.NextSibling; next != nil; next = next.NextSibling {
+		switch next.Type {
+		case html.TextNode:
+			if strings.TrimSpace(next.Data) != "" {
+				return false
+			}
+		case html.ElementNode:
+			if next.Data != "br" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isAfterLineBreak reports whether the node directly follows a `<br>`.
+func isAfterLineBreak(n *html.Node) bool {
+	prev := n.PrevSibling
+	return prev != nil && prev.Type == html.ElementNode && prev.Data == "br"
+}
+
+// isAtLineStart returns whether the text node starts a line of the output:
+// nothing is rendered before it inside of its block, or it follows a `<br>`.
+func isAtLineStart(n *html.Node) bool {
+	for {
+		if prev := previousContent(n); prev != nil {
+			return prev.Type == html.ElementNode && (prev.Data == "br" || !IsInlineElement(prev.Data))
+		}
+
+		parent := n.Parent
+		if parent == nil || parent.Type != html.ElementNode || !IsInlineElement(parent.Data) {
+			return true
+		}
+		n = parent
+	}
+}
+
+// previousContent returns the previous sibling that is rendered,
+// skipping comments and text nodes with only whitespace.
+func previousContent(n *html.Node) *html.Node {
+	prev := n.PrevSibling
+	for prev != nil && (prev.Type == html.CommentNode ||
+		(prev.Type == html.TextNode && strings.TrimSpace(prev.Data) == "")) {
+		prev = prev.PrevSibling
+	}
+	return prev
+}
+
+// lineBreakElements are converted to a new line by the commonmark rules.
+// Other (also unknown) elements are converted to their content.
+var lineBreakElements = map[string]bool{
+	"br": true, "p": true, "div": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "ul": true, "ol": true, "li": true,
+	"blockquote": true, "pre": true, "hr": true, "body": true, "html": true,
+}
+
+// lineTextBefore returns the text in front of the node
+// that is on the same line of the output.
+func lineTextBefore(n *html.Node) string {
+	var text string
+	for {
+		for prev := previousContent(n); prev != nil; prev = previousContent(prev) {
+			if prev.Type == html.ElementNode && lineBreakElements[prev.Data] {
+				return text
+			}
+			text = CollectText(prev) + text
+		}
+
+		parent := n.Parent
+		if parent == nil || parent.Type != html.ElementNode || lineBreakElements[parent.Data] {
+			return text
+		}
+		n = parent
+	}
+}
+
+// endsWithLineBreak reports whether the markdown of the node ends with a line
+// break, like the `lineBreakElements` or a text node that ends with a newline.
+func endsWithLineBreak(n *html.Node) bool {
+	switch {
+	case n == nil:
+		return false
+	case n.Type == html.TextNode:
+		return strings.HasSuffix(strings.TrimRight(n.Data, " \t"), "\n")
+	case n.Type == html.ElementNode:
+		return lineBreakElements[n.Data]
+	}
+	return false
+}
+
+// TrimTrailingSpacesKeepLineBreaks is like `TrimTrailingSpaces` but keeps the
+// two trailing spaces of a hard line break, if the next line is not empty.
+func TrimTrailingSpacesKeepLineBreaks(text string) string {
+	parts := strings.Split(text, "\n")
+	for i := range parts {
+		trimmed := strings.TrimRightFunc(parts[i], unicode.IsSpace)
+
+		isBreak := strings.HasSuffix(parts[i], "  ") && trimmed != "" &&
+			i+1 < len(parts) && strings.TrimSpace(parts[i+1]) != ""
+		if isBreak {
+			trimmed += "  "
+		}
+		parts[i] = trimmed
+	}
+
+	return strings.Join(parts, "\n")
+}
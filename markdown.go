@@ -5,6 +5,7 @@ import (
 	"log"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -61,6 +62,31 @@ type Options struct {
 	// default: "atx"
 	HeadingStyle string
 
+	// HeadingOffset is added to the level of every heading, for example
+	// 1 turns a `<h1>` into "##" to embed the markdown under an existing title.
+	HeadingOffset int
+
+	// HeadingMaxLevel is the deepest level of a heading. The headings that
+	// are deeper (also because of the `HeadingOffset`) become bold paragraphs.
+	// default: 6
+	HeadingMaxLevel int
+
+	// HeadingRenumber removes the gaps between the levels, so that the first
+	// heading is "#" and a `<h4>` after a `<h2>` becomes "###".
+	HeadingRenumber bool
+
+	// HeadingSingleH1 turns every `<h1>` except the first one into "##",
+	// the headings inside of their sections are also one level deeper.
+	HeadingSingleH1 bool
+
+	// none, attribute, anchor or github
+	//   - "attribute" keeps the id of the heading: "## Title {#id}"
+	//   - "anchor" keeps the id with an anchor: "## <a id="id"></a>Title"
+	//   - "github" rewrites the links to the heading to the slug that GitHub generates
+	// In-page links ("#id") to a heading are kept relative unless the style is "none".
+	// default: none
+	HeadingIDStyle string
+
 	// Any Thematic break
 	// default: "* * *"
 	HorizontalRule string
@@ -85,10 +111,18 @@ type Options struct {
 	// default: **
 	StrongDelimiter string
 
-	// inlined or referenced
+	// inlined, referenced, numbered or text
+	//   - "numbered" keeps the text followed by a number ("text [1]")
+	//     and lists the urls at the end
+	//   - "text" removes the links but keeps the text
 	// default: inlined
 	LinkStyle string
 
+	// LinkCollapseLength is the length of the url from which
+	// an inlined link is turned into a referenced link.
+	// default: 0 (never)
+	LinkCollapseLength int
+
 	// full, collapsed, or shortcut
 	// default: full
 	LinkReferenceStyle string
@@ -97,8 +131,78 @@ type Options struct {
 	// default: basic
 	EscapeMode string
 
+	// ImagePreferredWidth is the width (in pixels) that is preferred when
+	// choosing between the candidates of a `srcset` or `<picture>`. The smallest
+	// candidate that is at least as wide is used.
+	// default: 0 (the largest candidate)
+	ImagePreferredWidth int
+
+	// inlined, referenced, alt, html or none
+	//   - "referenced" collects the urls at the end like the links
+	//   - "alt" only keeps the alt text
+	//   - "html" keeps an `<img>` tag with the width and height
+	//   - "none" removes the images
+	// default: inlined
+	ImageStyle string
+
+	// ImageDataURILimit is the maximum length (in bytes) of a data uri
+	// image. Longer data uris are replaced with `ImageDataURIPlaceholder`.
+	// default: 0 (no limit)
+	ImageDataURILimit int
+
+	// ImageDataURIPlaceholder replaces the data uris that are too long.
+	// default: "data:,"
+	ImageDataURIPlaceholder string
+
+	// paragraph, spaces, backslash or html
+	// The markdown for a `<br>`:
+	//   - "paragraph" starts a new paragraph
+	//   - "spaces" is a hard line break with two trailing spaces
+	//   - "backslash" is a hard line break with a trailing backslash
+	//   - "html" keeps the `<br>`
+	// Inside of table cells, headings and links the line break is adjusted.
+	// default: paragraph
+	LineBreakStyle string
+
+	// collapse, linebreaks or codeblock
+	// How the text of the elements that preserve their whitespace is converted,
+	// for example elements with a "white-space: pre" style, `<xmp>` or `<textarea>`:
+	//   - "collapse" collapses the whitespace like in every other element
+	//   - "linebreaks" keeps the lines with hard line breaks and the indentation
+	//   - "codeblock" turns block elements into code blocks, like the `<pre>` elements
+	// default: collapse
+	PreformattedStyle string
+
+	// PreformattedClasses are the classes that preserve the whitespace. They
+	// are added to the default classes (e.g. "whitespace-pre" of Tailwind).
+	PreformattedClasses []string
+
+	// RemoveHidden removes the content that is not visible in the browser:
+	// elements with the `hidden` or `aria-hidden="true"` attribute, an inline
+	// "display: none" or "visibility: hidden" style, a hidden class and `<template>`.
+	RemoveHidden bool
+
+	// HiddenClasses are the classes that hide an element. They are
+	// added to the default classes (e.g. "d-none" or "hidden"). An element
+	// that is shown again on larger screens (e.g. "hidden md:block") is kept.
+	HiddenClasses []string
+
+	// RemoveScreenReaderOnly removes the text that is only meant for
+	// screen readers (e.g. "sr-only" or "visually-hidden"), unless it is
+	// the only label of a link or button.
+	RemoveScreenReaderOnly bool
+
 	domain string
 
+	// the numbers of the reference links and images, which are
+	// assigned while converting (see `Convert`)
+	references      *linkReferences
+	imageReferences *linkReferences
+
+	// the results of the `AssetHandler`, which are
+	// requested before converting (see `prefetchAssets`)
+	assets map[assetKey]string
+
 	// GetAbsoluteURL parses the `rawURL` and adds the `domain` to convert relative (/page.html)
 	// urls to absolute urls (http://domain.com/page.html).
 	//
@@ -106,14 +210,40 @@ type Options struct {
 	// be useful if you want to proxy the images.
 	GetAbsoluteURL func(selec *goquery.Selection, rawURL string, domain string) string
 
+	// URLTransformers rewrite the urls of the links, images and iframes after
+	// they were made absolute. They are called in order, for example to remove
+	// tracking parameters (`RemoveTrackingParameters`) or to unwrap redirects
+	// (`UnwrapRedirects`).
+	URLTransformers []URLTransformer
+
+	// AllowUnsafeURLs keeps the urls with schemes like "javascript:",
+	// that are otherwise removed by `SafeURL`.
+	AllowUnsafeURLs bool
+
+	// AssetHandler is called with the absolute url of every image and link. It
+	// can save the asset (for example for an offline archive) and return the
+	// rewritten url. Return the `rawURL` to keep the url. The handler is called
+	// concurrently for the images and links of a document.
+	//
+	// `LocalAssetHandler` saves the assets into a local directory.
+	AssetHandler func(selec *goquery.Selection, rawURL string) string
+
 	// GetCodeBlockLanguage identifies the language for syntax highlighting
 	// of a code block. The default is `DefaultGetCodeBlockLanguage`, which
-	// only gets the attribute x from the selection.
+	// understands the attributes and classes of the common syntax highlighters.
 	//
 	// You can override it if you want more results, for example by using
 	// lexers.Analyse(content) from github.com/alecthomas/chroma
-	// TODO: implement
-	// GetCodeBlockLanguage func(s *goquery.Selection, content string) string
+	GetCodeBlockLanguage func(s *goquery.Selection, content string) string
+
+	// NormalizeCodeBlockLanguage replaces the aliases of the languages with
+	// the names that most renderers understand, e.g. "js" with "javascript"
+	// (`NormalizeCodeBlockLanguage`). By default the language is kept as it is.
+	NormalizeCodeBlockLanguage bool
+
+	// GuessCodeBlockLanguage guesses the language of the code blocks
+	// without a language from their content (`GuessCodeBlockLanguage`).
+	GuessCodeBlockLanguage bool
 }
 
 // DefaultGetAbsoluteURL is the default function and can be overridden through `GetAbsoluteURL` in the options.
@@ -143,6 +273,29 @@ func DefaultGetAbsoluteURL(selec *goquery.Selection, rawURL string, domain strin
 	return u.String()
 }
 
+// linkReferences numbers the reference links of one conversion. The numbers
+// are only assigned to links that are rendered, so there are no gaps and
+// every number has a definition.
+type linkReferences struct {
+	ids map[string]string
+}
+
+func newLinkReferences() *linkReferences {
+	return &linkReferences{ids: make(map[string]string)}
+}
+
+// id returns the number of the link. Links with the same href and title
+// share the number, isNew is only true for the first one of them.
+func (r *linkReferences) id(href, title string) (id string, isNew bool) {
+	key := href + "\x00" + title
+	if id, ok := r.ids[key]; ok {
+		return id, false
+	}
+	id = strconv.Itoa(len(r.ids) + 1)
+	r.ids[key] = id
+	return id, true
+}
+
 // AdvancedResult is used for example for links. If you use LinkStyle:referenced
 // the link href is placed at the bottom of the generated markdown (Footer).
 type AdvancedResult struct {
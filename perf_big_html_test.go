@@ -90,3 +90,54 @@ func TestPerfBigTable_GitHubFlavored(t *testing.T) {
 		t.Fatalf("expected non-empty markdown output")
 	}
 }
+
+// TestPerfBigTable_RowSpans converts a table with 6,000 rows in one tbody.
+// The rowspan of the cells is limited to the section, which has to
+// be computed once per table and not for every cell.
+//
+//	go test -run '^TestPerfBigTable_RowSpans$' -count=1
+func TestPerfBigTable_RowSpans(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<table><thead><tr><th>A</th><th>B</th><th>C</th><th>D</th><th>E</th></tr></thead><tbody>")
+	for i := 0; i < 6000; i++ {
+		b.WriteString("<tr><td>1</td><td>2</td><td>3</td><td>4</td><td>5</td></tr>")
+	}
+	b.WriteString("</tbody></table>")
+
+	conv := md.NewConverter("", true, nil)
+	conv.Use(plugin.Table())
+	out, err := conv.ConvertString(b.String())
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if strings.Count(out, "\n") != 6001 {
+		t.Fatalf("expected a row for every tr but got %d lines", strings.Count(out, "\n")+1)
+	}
+}
+
+// TestPerfBigTable_ColSpans converts a small table with huge colspans.
+// Padding every row to the widest row would produce megabytes of
+// markdown, so the cells are kept in source order above the limit.
+//
+//	go test -run '^TestPerfBigTable_ColSpans$' -count=1
+func TestPerfBigTable_ColSpans(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<table>")
+	for i := 0; i < 200; i++ {
+		b.WriteString(`<tr><td colspan="1000">x</td></tr>`)
+	}
+	for i := 0; i < 20; i++ {
+		b.WriteString("<tr><td>y</td></tr>")
+	}
+	b.WriteString("</table>")
+
+	conv := md.NewConverter("", true, nil)
+	conv.Use(plugin.Table())
+	out, err := conv.ConvertString(b.String())
+	if err != nil {
+		t.Fatalf("convert: %v", err)
+	}
+	if len(out) > 10*b.Len() {
+		t.Fatalf("expected the output to stay small but got %d bytes", len(out))
+	}
+}
@@ -0,0 +1,226 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+)
+
+const (
+	attrAdmonitionType  = "data-converter-admonition"
+	attrAdmonitionTitle = "data-converter-admonition-title"
+)
+
+// AdmonitionOptions configures the `Admonition` plugin.
+type AdmonitionOptions struct {
+	// Style of the generated markdown:
+	//   - "github" (default) GitHub alerts: "> [!NOTE]"
+	//   - "obsidian" Obsidian callouts: "> [!note] Title"
+	//   - "mkdocs" the admonitions of Python-Markdown: "!!! note "Title""
+	Style string
+
+	// Classes maps class names to the type of the admonition (e.g. "note",
+	// "tip", "warning"). They are added to the default classes.
+	Classes map[string]string
+}
+
+// admonitionTypes are the types that are recognized in class names like
+// "admonition-note", "alert-warning" or "markdown-alert-tip".
+var admonitionTypes = []string{
+	"note", "info", "tip", "hint", "success", "important", "warning", "attention",
+	"caution", "danger", "error", "bug", "question", "example", "quote", "abstract",
+}
+
+var admonitionClassPrefixes = []string{
+	"admonition-", "theme-admonition-", "markdown-alert-", "alert-", "callout-",
+}
+
+// githubAlertTypes maps the types to the five types that GitHub supports.
+var githubAlertTypes = map[string]string{
+	"note":      "NOTE",
+	"info":      "NOTE",
+	"question":  "NOTE",
+	"example":   "NOTE",
+	"quote":     "NOTE",
+	"abstract":  "NOTE",
+	"tip":       "TIP",
+	"hint":      "TIP",
+	"success":   "TIP",
+	"important": "IMPORTANT",
+	"warning":   "WARNING",
+	"attention": "WARNING",
+	"caution":   "CAUTION",
+	"danger":    "CAUTION",
+	"error":     "CAUTION",
+	"bug":       "CAUTION",
+}
+
+// admonitionTitles are the elements that contain the title of the admonition.
+var admonitionTitles = strings.Join([]string{
+	".admonition-title",
+	".admonition-heading",
+	"[class*='admonitionHeading']",
+	".markdown-alert-title",
+	".alert-heading",
+	".callout-title",
+}, ", ")
+
+// Admonition converts callouts (Docusaurus, MkDocs, Sphinx, Bootstrap alerts,
+// GitHub alerts and the info/note/tip/warning macros of Confluence) into
+// GitHub alerts, Obsidian callouts or MkDocs admonitions.
+func Admonition(options *AdmonitionOptions) md.Plugin {
+	var admonitionOpt AdmonitionOptions
+	if options != nil {
+		admonitionOpt = *options
+	}
+	if admonitionOpt.Style == "" {
+		admonitionOpt.Style = "github"
+	}
+
+	classes := make(map[string]string)
+	for _, t := range admonitionTypes {
+		for _, prefix := range admonitionClassPrefixes {
+			classes[prefix+t] = t
+		}
+	}
+	for class, t := range admonitionOpt.Classes {
+		classes[class] = strings.ToLower(t)
+	}
+
+	return func(c *md.Converter) []md.Rule {
+		c.Before(func(selec *goquery.Selection) {
+			annotateAdmonitions(selec, classes)
+		})
+
+		return []md.Rule{
+			{
+				Filter: []string{"div", "aside", "section", "blockquote", "ac:structured-macro"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					t, ok := selec.Attr(attrAdmonitionType)
+					if !ok {
+						return nil
+					}
+					title := selec.AttrOr(attrAdmonitionTitle, "")
+					content = strings.TrimSpace(content)
+
+					var text string
+					switch admonitionOpt.Style {
+					case "obsidian":
+						text = "[!" + t + "]"
+						if title != "" {
+							text += " " + title
+						}
+						if content != "" {
+							text += "\n" + content
+						}
+						text = md.QuoteBlock(text)
+					case "mkdocs":
+						text = "!!! " + t
+						if title != "" {
+							text += ` "` + strings.ReplaceAll(title, `"`, `\"`) + `"`
+						}
+						if content != "" {
+							text += "\n\n" + indentLines(content, "    ", false)
+						}
+					default:
+						alert, ok := githubAlertTypes[t]
+						if !ok {
+							alert = "NOTE"
+						}
+						text = "[!" + alert + "]"
+						if title != "" {
+							// GitHub has no syntax for a custom title
+							text += "\n" + opt.StrongDelimiter + title + opt.StrongDelimiter + "\n"
+						}
+						if content != "" {
+							text += "\n" + content
+						}
+						text = md.QuoteBlock(text)
+					}
+
+					return md.String("\n\n" + text + "\n\n")
+				},
+			},
+		}
+	}
+}
+
+// annotateAdmonitions marks the admonitions with their type and
+// custom title. The elements of the title are removed.
+func annotateAdmonitions(selec *goquery.Selection, classes map[string]string) {
+	selec.Find("div, aside, section, blockquote").Each(func(i int, s *goquery.Selection) {
+		t := admonitionType(s, classes)
+		if t == "" {
+			return
+		}
+
+		title := s.ChildrenFiltered(admonitionTitles).First()
+		setAdmonition(s, t, strings.Join(strings.Fields(title.Text()), " "))
+		title.Remove()
+
+		// for example the close button of bootstrap alerts
+		s.Find("button").Remove()
+	})
+
+	selec.Find("*").Each(func(i int, s *goquery.Selection) {
+		if goquery.NodeName(s) != "ac:structured-macro" {
+			return
+		}
+		name := strings.ToLower(s.AttrOr("ac:name", ""))
+		if name != "info" && name != "note" && name != "tip" && name != "warning" {
+			return
+		}
+
+		var title string
+		s.Children().Each(func(i int, param *goquery.Selection) {
+			if goquery.NodeName(param) != "ac:parameter" {
+				return
+			}
+			if param.AttrOr("ac:name", "") == "title" {
+				title = strings.TrimSpace(param.Text())
+			}
+			param.Remove()
+		})
+		setAdmonition(s, name, title)
+	})
+}
+
+func setAdmonition(s *goquery.Selection, t, title string) {
+	s.SetAttr(attrAdmonitionType, t)
+
+	// the title is only kept if it is not the same as the type
+	if title != "" && !strings.EqualFold(title, t) && !strings.EqualFold(title, githubAlertTypes[t]) {
+		s.SetAttr(attrAdmonitionTitle, title)
+	}
+}
+
+// admonitionType returns the type of the admonition or an empty string
+// if the element is not an admonition.
+func admonitionType(s *goquery.Selection, classes map[string]string) string {
+	names := strings.Fields(s.AttrOr("class", ""))
+	for _, name := range names {
+		if t, ok := classes[name]; ok {
+			return t
+		}
+	}
+
+	// MkDocs and Sphinx: `<div class="admonition warning">`
+	var isAdmonition bool
+	for _, name := range names {
+		if name == "admonition" {
+			isAdmonition = true
+		}
+	}
+	if !isAdmonition {
+		return ""
+	}
+	for _, name := range names {
+		for _, t := range admonitionTypes {
+			if name == t {
+				return t
+			}
+		}
+	}
+	return "note"
+}
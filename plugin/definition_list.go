@@ -0,0 +1,131 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+)
+
+// DefinitionListOptions configures the DefinitionList plugin.
+type DefinitionListOptions struct {
+	// Style of the generated markdown:
+	//   - "extra" (default) the syntax of PHP Markdown Extra and Pandoc: "Term\n: Definition"
+	//   - "bold" for plain commonmark: the term in bold followed by an indented paragraph
+	//   - "list" a bullet list: "- **Term**: Definition"
+	Style string
+}
+
+// definitionIndent is used for the indentation of the "bold" style, since the
+// spaces would be removed by a surrounding `<div>`. It is replaced with a
+// space after the conversion.
+const definitionIndent = "\uE000"
+
+// DefinitionList converts `<dl>`, `<dt>` and `<dd>` elements.
+func DefinitionList(options *DefinitionListOptions) md.Plugin {
+	var definitionOpt DefinitionListOptions
+	if options != nil {
+		definitionOpt = *options
+	}
+	if definitionOpt.Style == "" {
+		definitionOpt.Style = "extra"
+	}
+	style := definitionOpt.Style
+
+	return func(c *md.Converter) []md.Rule {
+		if style == "bold" {
+			c.After(func(markdown string) string {
+				return strings.ReplaceAll(markdown, definitionIndent, " ")
+			})
+		}
+
+		return []md.Rule{
+			{
+				Filter: []string{"dl"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					content = strings.Trim(content, "\n")
+					if strings.TrimSpace(content) == "" {
+						return md.String("")
+					}
+
+					return md.String("\n\n" + content + "\n\n")
+				},
+			},
+			{
+				Filter: []string{"dt"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					term := strings.Join(strings.Fields(content), " ")
+					if term == "" {
+						return md.String("")
+					}
+
+					// Multiple terms can share the same definitions.
+					prefix := "\n\n"
+					if selec.Prev().Is("dt") {
+						prefix = "\n"
+					}
+
+					switch style {
+					case "bold":
+						return md.String("\n\n" + opt.StrongDelimiter + term + opt.StrongDelimiter)
+					case "list":
+						return md.String("\n" + opt.BulletListMarker + " " + opt.StrongDelimiter + term + opt.StrongDelimiter)
+					default:
+						return md.String(prefix + term)
+					}
+				},
+			},
+			{
+				Filter: []string{"dd"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					content = strings.Trim(content, "\n")
+					content = md.TrimTrailingSpaces(content)
+					if strings.TrimSpace(content) == "" {
+						return md.String("")
+					}
+
+					switch style {
+					case "bold":
+						indent := strings.Repeat(definitionIndent, 2)
+						return md.String("\n\n" + indentLines(content, indent, false))
+					case "list":
+						// A single definition with a single line is placed next to the term,
+						// otherwise the definitions are nested below the term.
+						isOnly := selec.Prev().Is("dt") && !selec.Next().Is("dd")
+						if isOnly && !strings.Contains(content, "\n") {
+							return md.String(": " + content)
+						}
+
+						indent := strings.Repeat(" ", len(opt.BulletListMarker)+1)
+						if !selec.Prev().Is("dt") && !selec.Prev().Is("dd") {
+							// a definition without a term
+							return md.String("\n" + opt.BulletListMarker + " " + indentLines(content, indent, true))
+						}
+
+						item := opt.BulletListMarker + " " + indentLines(content, indent+indent, true)
+						return md.String("\n" + indent + item)
+					default:
+						prefix := "\n"
+						if !selec.Prev().Is("dt") && !selec.Prev().Is("dd") {
+							// a definition without a term
+							prefix = "\n\n"
+						}
+						return md.String(prefix + ": " + indentLines(content, "    ", true))
+					}
+				},
+			},
+		}
+	}
+}
+
+// indentLines indents every line that is not empty.
+func indentLines(text string, indent string, skipFirst bool) string {
+	lines := strings.Split(text, "\n")
+	for i := range lines {
+		if (i == 0 && skipFirst) || lines[i] == "" {
+			continue
+		}
+		lines[i] = indent + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
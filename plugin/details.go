@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"html"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+	"github.com/firecrawl/html-to-markdown/escape"
+)
+
+// DetailsOptions configures the `Details` plugin.
+type DetailsOptions struct {
+	// Style of the generated markdown:
+	//   - "html" (default) the `<details>` and `<summary>` are kept as html
+	//     (GitHub renders them) but the content is converted to markdown
+	//   - "bold" the summary is a bold line followed by the content
+	Style string
+
+	// RemoveClosed removes the `<details>` elements that don't have the
+	// `open` attribute, for example if the output is used for a LLM.
+	RemoveClosed bool
+}
+
+// Details converts the collapsible `<details>` and `<summary>` elements.
+func Details(options *DetailsOptions) md.Plugin {
+	var detailsOpt DetailsOptions
+	if options != nil {
+		detailsOpt = *options
+	}
+	if detailsOpt.Style == "" {
+		detailsOpt.Style = "html"
+	}
+
+	return func(c *md.Converter) []md.Rule {
+		return []md.Rule{
+			{
+				Filter: []string{"details"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					_, isOpen := selec.Attr("open")
+					if detailsOpt.RemoveClosed && !isOpen {
+						return md.String("")
+					}
+
+					summary := strings.Join(strings.Fields(selec.ChildrenFiltered("summary").First().Text()), " ")
+					content = strings.TrimSpace(content)
+
+					if detailsOpt.Style == "bold" {
+						text := content
+						if summary != "" {
+							text = opt.StrongDelimiter + escape.MarkdownCharacters(summary) + opt.StrongDelimiter + "\n\n" + content
+						}
+						return md.String("\n\n" + strings.TrimSpace(text) + "\n\n")
+					}
+
+					var b strings.Builder
+					b.WriteString("\n\n<details")
+					if isOpen {
+						b.WriteString(" open")
+					}
+					b.WriteString(">\n")
+					if summary != "" {
+						b.WriteString("<summary>" + html.EscapeString(summary) + "</summary>\n")
+					}
+					if content != "" {
+						// the blank lines are needed so that the markdown inside is rendered
+						b.WriteString("\n" + content + "\n\n")
+					}
+					b.WriteString("</details>\n\n")
+					return md.String(b.String())
+				},
+			},
+			{
+				Filter: []string{"summary"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if !selec.Parent().Is("details") {
+						return nil
+					}
+					// the details rule adds the summary
+					return md.String("")
+				},
+			},
+		}
+	}
+}
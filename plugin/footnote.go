@@ -0,0 +1,189 @@
+package plugin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+	"golang.org/x/net/html"
+)
+
+const (
+	attrFootnoteLabel = "data-converter-footnote-label"
+	attrFootnoteRef   = "data-converter-footnote-ref"
+	attrFootnoteList  = "data-converter-footnote-list"
+)
+
+// footnoteContainers are the elements that hold the footnote definitions
+// (pandoc, markdown-it, GitHub, Docusaurus, Wikipedia, ...).
+var footnoteContainers = strings.Join([]string{
+	"section.footnotes",
+	"div.footnotes",
+	"[role='doc-endnotes']",
+	"[data-footnotes]",
+	"ol.references",
+}, ", ")
+
+// footnoteIDR matches the ids of footnote definitions outside of a footnote
+// container (e.g. "fn1", "fn:1", "footnote-2" or "cite_note-3").
+var footnoteIDR = regexp.MustCompile(`(?i)^(user-content-)?(fn|footnote|cite_note)([-_:.]|\d|$)`)
+
+// footnoteReferences are links that could point to a footnote definition.
+var footnoteReferences = strings.Join([]string{
+	"sup a[href^='#']",
+	"a[role='doc-noteref']",
+	"a[data-footnote-ref]",
+	"a.footnote-ref",
+}, ", ")
+
+// footnoteBacklinks are the links from the definition back to the reference.
+var footnoteBacklinks = strings.Join([]string{
+	"[role='doc-backlink']",
+	"[data-footnote-backref]",
+	".footnote-back",
+	".footnote-backref",
+	".mw-cite-backlink",
+}, ", ")
+
+// Footnotes converts footnote references into `[^1]` and moves the footnote
+// definitions to the end of the document (`[^1]: The note`).
+//
+// The references are recognized by links to a list item inside of
+// a footnote section (for example `<sup><a href="#fn1">1</a></sup>`).
+// The links from the definitions back to the references are removed.
+func Footnotes() md.Plugin {
+	return func(c *md.Converter) []md.Rule {
+		c.Before(annotateFootnotes)
+
+		return []md.Rule{
+			{
+				Filter: []string{"a"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					label, ok := selec.Attr(attrFootnoteRef)
+					if !ok {
+						return nil
+					}
+					return md.String("[^" + label + "]")
+				},
+			},
+			{
+				Filter: []string{"ol", "ul"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					if _, ok := selec.Attr(attrFootnoteList); !ok {
+						return nil
+					}
+					// the definitions were moved to the footer
+					return md.String(strings.TrimSpace(content))
+				},
+			},
+			{
+				Filter: []string{"li", "div", "aside", "p"},
+				AdvancedReplacement: func(content string, selec *goquery.Selection, opt *md.Options) (md.AdvancedResult, bool) {
+					label, ok := selec.Attr(attrFootnoteLabel)
+					if !ok {
+						return md.AdvancedResult{}, true
+					}
+
+					content = strings.Trim(content, "\n")
+					content = md.TrimTrailingSpaces(content)
+					content = strings.TrimSpace(content)
+
+					def := "[^" + label + "]: " + indentLines(content, "    ", true)
+					return md.AdvancedResult{Footer: def + "\n\n"}, false
+				},
+			},
+		}
+	}
+}
+
+// annotateFootnotes finds the footnote definitions and the references to them
+// and marks them with a label.
+func annotateFootnotes(selec *goquery.Selection) {
+	ids := make(map[string]*goquery.Selection)
+	selec.Find("[id]").Each(func(i int, s *goquery.Selection) {
+		id := s.AttrOr("id", "")
+		if _, ok := ids[id]; !ok {
+			ids[id] = s
+		}
+	})
+
+	definitions := make(map[*html.Node]bool)
+	selec.Find(footnoteContainers).Each(func(i int, container *goquery.Selection) {
+		container.Find("li[id]").Each(func(i int, s *goquery.Selection) {
+			definitions[s.Get(0)] = true
+		})
+	})
+
+	isDefinition := func(s *goquery.Selection) bool {
+		if s.Is("li") {
+			// a link to a normal list item is not a footnote
+			return definitions[s.Get(0)] || footnoteIDR.MatchString(s.AttrOr("id", ""))
+		}
+		role := s.AttrOr("role", "")
+		return role == "doc-endnote" || role == "doc-footnote"
+	}
+
+	type reference struct {
+		link   *goquery.Selection
+		target *goquery.Selection
+	}
+	var references []reference
+	selec.Find(footnoteReferences).Each(func(i int, link *goquery.Selection) {
+		target, ok := ids[strings.TrimPrefix(link.AttrOr("href", ""), "#")]
+		if !ok || !isDefinition(target) {
+			return
+		}
+		// a link inside of a definition is not a reference
+		if parent := link.Closest("li, [role='doc-endnote'], [role='doc-footnote']"); parent.Length() > 0 && definitions[parent.Get(0)] {
+			return
+		}
+
+		definitions[target.Get(0)] = true
+		references = append(references, reference{link: link, target: target})
+	})
+	if len(definitions) == 0 {
+		return
+	}
+
+	// The labels are numbered in the order of the definitions.
+	var count int
+	selec.Find("li, [role='doc-endnote'], [role='doc-footnote']").Each(func(i int, s *goquery.Selection) {
+		if !definitions[s.Get(0)] {
+			return
+		}
+		count++
+		s.SetAttr(attrFootnoteLabel, strconv.Itoa(count))
+
+		if s.Is("li") {
+			s.Parent().SetAttr(attrFootnoteList, "true")
+		}
+	})
+
+	backlinks := make(map[string]bool)
+	for _, ref := range references {
+		ref.link.SetAttr(attrFootnoteRef, ref.target.AttrOr(attrFootnoteLabel, ""))
+
+		// the backlink can point to the link or to the surrounding `sup`
+		for _, s := range []*goquery.Selection{ref.link, ref.link.Closest("sup")} {
+			if id, ok := s.Attr("id"); ok {
+				backlinks[id] = true
+			}
+		}
+	}
+
+	selec.Find("[" + attrFootnoteLabel + "]").Each(func(i int, def *goquery.Selection) {
+		def.Find(footnoteBacklinks).Remove()
+		def.Find("a[href^='#']").Each(func(i int, a *goquery.Selection) {
+			if backlinks[strings.TrimPrefix(a.AttrOr("href", ""), "#")] {
+				a.Remove()
+			}
+		})
+	})
+
+	// the separator and the visually hidden heading of the footnote section
+	selec.Find(footnoteContainers).Each(func(i int, container *goquery.Selection) {
+		container.ChildrenFiltered("hr, .sr-only, .visually-hidden").Remove()
+	})
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+	"golang.org/x/net/html"
+)
+
+const (
+	attrMath    = "data-converter-math"
+	attrMathTeX = "data-converter-math-tex"
+	mathInline  = "inline"
+	mathDisplay = "display"
+	texEncoding = "application/x-tex"
+)
+
+// mathJaxOutput are the elements that MathJax renders next to the source script.
+var mathJaxOutput = []string{
+	"MathJax", "MathJax_Display", "MathJax_Preview", "MathJax_SVG", "MathJax_SVG_Display",
+	"MathJax_CHTML", "MathJax_MathML",
+}
+
+// Math converts math formulas into `$...$` (inline) and `$$...$$` (display).
+//
+// The TeX source is recovered from KaTeX (the `application/x-tex` annotation)
+// and MathJax (the `math/tex` scripts). Otherwise the MathML is translated to LaTeX.
+func Math() md.Plugin {
+	return func(c *md.Converter) []md.Rule {
+		c.Before(annotateMath)
+
+		return []md.Rule{
+			{
+				Filter: []string{"span"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					mode, ok := selec.Attr(attrMath)
+					if !ok {
+						return nil
+					}
+
+					tex := strings.TrimSpace(selec.AttrOr(attrMathTeX, ""))
+					if tex == "" {
+						return md.String("")
+					}
+					if mode == mathDisplay {
+						return md.String("\n\n$$\n" + tex + "\n$$\n\n")
+					}
+
+					// inline math has to be on one line
+					tex = strings.Join(strings.Fields(tex), " ")
+					return md.String(md.AddSpaceIfNessesary(selec, "$"+tex+"$"))
+				},
+			},
+		}
+	}
+}
+
+// annotateMath replaces the different kinds of math markup
+// with a span that contains the TeX source.
+func annotateMath(selec *goquery.Selection) {
+	// KaTeX
+	selec.Find(".katex-display").Each(func(i int, s *goquery.Selection) {
+		replaceMath(s, mathDisplay, mathSource(s))
+	})
+	selec.Find(".katex").Each(func(i int, s *goquery.Selection) {
+		replaceMath(s, mathInline, mathSource(s))
+	})
+
+	// MathJax 2: the source is inside of a script, the rendered output is before it
+	selec.Find("script").Each(func(i int, s *goquery.Selection) {
+		t := strings.ToLower(s.AttrOr("type", ""))
+		if !strings.HasPrefix(t, "math/tex") {
+			return
+		}
+		for prev := s.Prev(); prev.Length() > 0 && isMathJaxOutput(prev); prev = s.Prev() {
+			prev.Remove()
+		}
+
+		mode := mathInline
+		if strings.Contains(t, "mode=display") {
+			mode = mathDisplay
+		}
+		replaceMath(s, mode, s.Text())
+	})
+
+	// MathJax 3: the rendered output contains the MathML for assistive technology
+	selec.Find("mjx-container").Each(func(i int, s *goquery.Selection) {
+		mode := mathInline
+		if s.AttrOr("display", "") == "true" {
+			mode = mathDisplay
+		}
+		replaceMath(s, mode, mathSource(s))
+	})
+
+	// MathML
+	selec.Find("math").Each(func(i int, s *goquery.Selection) {
+		mode := mathInline
+		if s.AttrOr("display", "") == "block" || s.AttrOr("mode", "") == "display" {
+			mode = mathDisplay
+		}
+		replaceMath(s, mode, mathSource(s))
+	})
+}
+
+func isMathJaxOutput(s *goquery.Selection) bool {
+	for _, class := range mathJaxOutput {
+		if s.HasClass(class) {
+			return true
+		}
+	}
+	return false
+}
+
+// mathSource returns the TeX from the annotation or translates the MathML.
+func mathSource(s *goquery.Selection) string {
+	annotation := s.Find("annotation").FilterFunction(func(i int, a *goquery.Selection) bool {
+		return strings.EqualFold(a.AttrOr("encoding", ""), texEncoding)
+	})
+	if annotation.Length() > 0 {
+		return annotation.First().Text()
+	}
+
+	math := s
+	if !s.Is("math") {
+		math = s.Find("math").First()
+	}
+	if math.Length() == 0 {
+		return ""
+	}
+	return mathMLToLaTeX(math.Get(0))
+}
+
+func replaceMath(s *goquery.Selection, mode, tex string) {
+	span := &html.Node{
+		Type: html.ElementNode,
+		Data: "span",
+		Attr: []html.Attribute{
+			{Key: attrMath, Val: mode},
+			{Key: attrMathTeX, Val: tex},
+		},
+	}
+	s.ReplaceWithNodes(span)
+}
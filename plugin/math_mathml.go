@@ -0,0 +1,239 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/firecrawl/html-to-markdown"
+	"golang.org/x/net/html"
+)
+
+// mathFunctions are written as `\sin` instead of `sin`.
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true,
+	"log": true, "ln": true, "lg": true, "exp": true, "lim": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "dim": true, "gcd": true, "deg": true, "arg": true,
+}
+
+// mathSymbols maps unicode characters to LaTeX commands.
+var mathSymbols = map[string]string{
+	"α": `\alpha`, "β": `\beta`, "γ": `\gamma`, "δ": `\delta`, "ε": `\epsilon`, "ϵ": `\epsilon`,
+	"ζ": `\zeta`, "η": `\eta`, "θ": `\theta`, "ι": `\iota`, "κ": `\kappa`, "λ": `\lambda`,
+	"μ": `\mu`, "ν": `\nu`, "ξ": `\xi`, "π": `\pi`, "ρ": `\rho`, "σ": `\sigma`, "τ": `\tau`,
+	"υ": `\upsilon`, "φ": `\phi`, "ϕ": `\phi`, "χ": `\chi`, "ψ": `\psi`, "ω": `\omega`,
+	"Γ": `\Gamma`, "Δ": `\Delta`, "Θ": `\Theta`, "Λ": `\Lambda`, "Ξ": `\Xi`, "Π": `\Pi`,
+	"Σ": `\Sigma`, "Φ": `\Phi`, "Ψ": `\Psi`, "Ω": `\Omega`,
+	"×": `\times`, "·": `\cdot`, "⋅": `\cdot`, "÷": `\div`, "±": `\pm`, "∓": `\mp`,
+	"≤": `\leq`, "≥": `\geq`, "≠": `\neq`, "≈": `\approx`, "≡": `\equiv`, "∼": `\sim`,
+	"∝": `\propto`, "∞": `\infty`, "∂": `\partial`, "∇": `\nabla`, "∈": `\in`, "∉": `\notin`,
+	"⊂": `\subset`, "⊆": `\subseteq`, "⊃": `\supset`, "∪": `\cup`, "∩": `\cap`, "∅": `\emptyset`,
+	"∀": `\forall`, "∃": `\exists`, "¬": `\neg`, "∧": `\wedge`, "∨": `\vee`,
+	"→": `\to`, "←": `\leftarrow`, "⇒": `\Rightarrow`, "⇐": `\Leftarrow`, "⇔": `\Leftrightarrow`,
+	"↦": `\mapsto`, "∑": `\sum`, "∏": `\prod`, "∫": `\int`, "∮": `\oint`, "√": `\sqrt`,
+	"…": `\ldots`, "⋯": `\cdots`, "°": `^\circ`, "′": `'`, "″": `''`, "−": "-", "∗": "*",
+	"{": `\{`, "}": `\}`, "%": `\%`, "#": `\#`, "&": `\&`, "_": `\_`,
+	// function application, invisible times, separator and plus
+	"\u2061": "", "\u2062": "", "\u2063": "", "\u2064": "",
+}
+
+// mathAccents maps the character of a `<mover accent>` to a LaTeX command.
+var mathAccents = map[string]string{
+	"^": `\hat`, "ˆ": `\hat`, "¯": `\overline`, "‾": `\overline`, "_": `\overline`,
+	"→": `\vec`, "\u20d7": `\vec`, "~": `\tilde`, "˜": `\tilde`, "˙": `\dot`, "¨": `\ddot`,
+	"⏞": `\overbrace`,
+}
+
+// mathLargeOperators get their limits with `_` and `^` instead of \underset and \overset.
+var mathLargeOperators = map[string]bool{
+	`\sum`: true, `\prod`: true, `\int`: true, `\oint`: true, `\lim`: true,
+	`\max`: true, `\min`: true, `\sup`: true, `\inf`: true,
+}
+
+var texCommandEndR = regexp.MustCompile(`\\[a-zA-Z]+$`)
+var texCommandR = regexp.MustCompile(`^\\[a-zA-Z]+$`)
+
+// mathMLToLaTeX translates the basic MathML elements to LaTeX.
+func mathMLToLaTeX(n *html.Node) string {
+	return strings.TrimSpace(mathNode(n))
+}
+
+func mathNode(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return mathText(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	children := mathChildren(n)
+	arg := func(i int) string {
+		if i < len(children) {
+			return mathNode(children[i])
+		}
+		return ""
+	}
+
+	switch n.Data {
+	case "annotation", "annotation-xml", "mphantom", "none", "mprescripts":
+		return ""
+	case "semantics":
+		return arg(0)
+	case "mi":
+		text := strings.TrimSpace(md.CollectText(n))
+		if mathFunctions[text] {
+			return `\` + text
+		}
+		if utf8.RuneCountInString(text) > 1 && mathText(text) == text {
+			// a multi letter identifier
+			return `\mathrm{` + text + `}`
+		}
+		return mathText(text)
+	case "mn":
+		return strings.TrimSpace(md.CollectText(n))
+	case "mo":
+		return mathText(strings.TrimSpace(md.CollectText(n)))
+	case "mtext":
+		text := md.CollectText(n)
+		if strings.TrimSpace(text) == "" {
+			return " "
+		}
+		return `\text{` + text + `}`
+	case "mspace":
+		return `\,`
+	case "msup":
+		return mathBase(arg(0)) + "^{" + arg(1) + "}"
+	case "msub":
+		return mathBase(arg(0)) + "_{" + arg(1) + "}"
+	case "msubsup":
+		return mathBase(arg(0)) + "_{" + arg(1) + "}^{" + arg(2) + "}"
+	case "mfrac":
+		return `\frac{` + arg(0) + "}{" + arg(1) + "}"
+	case "msqrt":
+		return `\sqrt{` + mathJoin(children) + "}"
+	case "mroot":
+		return `\sqrt[` + arg(1) + "]{" + arg(0) + "}"
+	case "mover":
+		base, over := arg(0), arg(1)
+		if len(children) == 2 {
+			if accent, ok := mathAccents[strings.TrimSpace(md.CollectText(children[1]))]; ok {
+				return accent + "{" + base + "}"
+			}
+		}
+		if mathLargeOperators[base] {
+			return base + "^{" + over + "}"
+		}
+		return `\overset{` + over + "}{" + base + "}"
+	case "munder":
+		base, under := arg(0), arg(1)
+		if mathLargeOperators[base] {
+			return base + "_{" + under + "}"
+		}
+		if len(children) == 2 && strings.TrimSpace(md.CollectText(children[1])) == "⏟" {
+			return `\underbrace{` + base + "}"
+		}
+		return `\underset{` + under + "}{" + base + "}"
+	case "munderover":
+		return mathBase(arg(0)) + "_{" + arg(1) + "}^{" + arg(2) + "}"
+	case "mfenced":
+		open := attr(n, "open", "(")
+		close := attr(n, "close", ")")
+		separator := attr(n, "separators", ",")
+		parts := make([]string, len(children))
+		for i, c := range children {
+			parts[i] = mathNode(c)
+		}
+		return `\left` + mathDelimiter(open) + strings.Join(parts, separator) + `\right` + mathDelimiter(close)
+	case "mtable":
+		var rows []string
+		for _, row := range children {
+			var cells []string
+			for _, cell := range mathChildren(row) {
+				cells = append(cells, mathJoin(mathChildren(cell)))
+			}
+			rows = append(rows, strings.Join(cells, " & "))
+		}
+		return `\begin{matrix}` + strings.Join(rows, ` \\ `) + `\end{matrix}`
+	}
+
+	// math, mrow, mstyle, mpadded, menclose, ...
+	return mathJoin(children)
+}
+
+// mathChildren returns the child elements and the text that is not only whitespace.
+func mathChildren(n *html.Node) []*html.Node {
+	var children []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode || (c.Type == html.TextNode && strings.TrimSpace(c.Data) != "") {
+			children = append(children, c)
+		}
+	}
+	return children
+}
+
+// mathJoin concatenates the LaTeX of the nodes, a space is added
+// if a command would otherwise run into the next letter.
+func mathJoin(nodes []*html.Node) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		part := mathNode(n)
+		if part == "" {
+			continue
+		}
+		first, _ := utf8.DecodeRuneInString(part)
+		isLetter := (first >= 'a' && first <= 'z') || (first >= 'A' && first <= 'Z')
+		if isLetter && texCommandEndR.MatchString(b.String()) {
+			b.WriteString(" ")
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// mathBase wraps the base of a script in braces if it has more than one part.
+func mathBase(base string) string {
+	if utf8.RuneCountInString(base) <= 1 || texCommandR.MatchString(base) {
+		return base
+	}
+	return "{" + base + "}"
+}
+
+func mathText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if symbol, ok := mathSymbols[string(r)]; ok {
+			b.WriteString(symbol)
+			continue
+		}
+		if texCommandEndR.MatchString(b.String()) && ((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			b.WriteString(" ")
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func mathDelimiter(d string) string {
+	switch d {
+	case "":
+		return "."
+	case "{":
+		return `\{`
+	case "}":
+		return `\}`
+	case "|", "‖":
+		return "|"
+	}
+	return d
+}
+
+func attr(n *html.Node, key, fallback string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return fallback
+}
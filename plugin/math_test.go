@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestMathMLToLaTeX(t *testing.T) {
+	tests := []struct {
+		mathml string
+		latex  string
+	}{
+		{`<mi>x</mi><mo>+</mo><mn>1</mn>`, `x+1`},
+		{`<msup><mi>x</mi><mn>2</mn></msup>`, `x^{2}`},
+		{`<msup><mrow><mi>x</mi><mo>+</mo><mn>1</mn></mrow><mn>2</mn></msup>`, `{x+1}^{2}`},
+		{`<msub><mi>a</mi><mi>i</mi></msub>`, `a_{i}`},
+		{`<mfrac><mn>1</mn><mi>n</mi></mfrac>`, `\frac{1}{n}`},
+		{`<msqrt><mi>x</mi></msqrt>`, `\sqrt{x}`},
+		{`<mroot><mi>x</mi><mn>3</mn></mroot>`, `\sqrt[3]{x}`},
+		{`<mi>α</mi><mi>x</mi>`, `\alpha x`},
+		{`<mi>sin</mi><mo>&#x2061;</mo><mi>θ</mi>`, `\sin\theta`},
+		{`<munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><mi>i</mi>`, `\sum_{i=1}^{n}i`},
+		{`<mover><mi>v</mi><mo>→</mo></mover>`, `\vec{v}`},
+		{`<mfenced><mi>a</mi><mi>b</mi></mfenced>`, `\left(a,b\right)`},
+		{`<mtable><mtr><mtd><mn>1</mn></mtd><mtd><mn>0</mn></mtd></mtr><mtr><mtd><mn>0</mn></mtd><mtd><mn>1</mn></mtd></mtr></mtable>`, `\begin{matrix}1 & 0 \\ 0 & 1\end{matrix}`},
+		{`<mtext>if </mtext><mi>x</mi><mo>≤</mo><mn>0</mn>`, `\text{if }x\leq0`},
+	}
+	for _, test := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader("<math>" + test.mathml + "</math>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latex := mathMLToLaTeX(doc.Find("math").Get(0)); latex != test.latex {
+			t.Errorf("expected %q but got %q for %s", test.latex, latex, test.mathml)
+		}
+	}
+}
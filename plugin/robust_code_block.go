@@ -20,20 +20,6 @@ func RobustCodeBlock() md.Plugin {
 			return strings.Contains(lower, "gutter") || strings.Contains(lower, "line-numbers")
 		}
 
-		detectLang := func(sel *goquery.Selection) string {
-			classes := sel.AttrOr("class", "")
-			lower := strings.ToLower(classes)
-			for _, part := range strings.Fields(lower) {
-				if strings.HasPrefix(part, "language-") {
-					return strings.TrimPrefix(part, "language-")
-				}
-				if strings.HasPrefix(part, "lang-") {
-					return strings.TrimPrefix(part, "lang-")
-				}
-			}
-			return ""
-		}
-
 		// collect extracts text recursively, inserting newlines after block elements and br
 		var collect func(n *html.Node, b *strings.Builder)
 		collect = func(n *html.Node, b *strings.Builder) {
@@ -73,18 +59,12 @@ func RobustCodeBlock() md.Plugin {
 		preRule := md.Rule{
 			Filter: []string{"pre"},
 			Replacement: func(_ string, selec *goquery.Selection, opt *md.Options) *string {
-				// Find inner <code> if present for language detection
-				codeSel := selec.Find("code").First()
-				lang := detectLang(codeSel)
-				if lang == "" {
-					lang = detectLang(selec)
-				}
-
 				var b strings.Builder
 				for _, n := range selec.Nodes {
 					collect(n, &b)
 				}
 				content := strings.TrimRight(b.String(), "\n")
+				lang := md.CodeBlockLanguage(selec, content, opt)
 
 				fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
 				fence := md.CalculateCodeFence(fenceChar, content)
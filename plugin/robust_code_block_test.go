@@ -28,6 +28,16 @@ func TestRobustCodeBlock_LanguageDetection(t *testing.T) {
 			html:     `<pre class="language-go"><code>func main() {}</code></pre>`,
 			expected: "```go",
 		},
+		{
+			name:     "highlight.js class without prefix",
+			html:     `<pre><code class="hljs py">print("hello")</code></pre>`,
+			expected: "```py",
+		},
+		{
+			name:     "rouge wrapper",
+			html:     `<div class="language-rb highlighter-rouge"><div class="highlight"><pre class="highlight"><code>puts 1</code></pre></div></div>`,
+			expected: "```rb",
+		},
 		{
 			name:     "no language class",
 			html:     `<pre><code>plain code</code></pre>`,
@@ -52,6 +62,19 @@ func TestRobustCodeBlock_LanguageDetection(t *testing.T) {
 	}
 }
 
+func TestRobustCodeBlock_NormalizeLanguage(t *testing.T) {
+	conv := md.NewConverter("", true, &md.Options{NormalizeCodeBlockLanguage: true})
+	conv.Use(RobustCodeBlock())
+
+	markdown, err := conv.ConvertString(`<pre><code class="hljs py">print("hello")</code></pre>`)
+	if err != nil {
+		t.Fatalf("ConvertString failed: %v", err)
+	}
+	if !strings.Contains(markdown, "```python") {
+		t.Errorf("expected the normalized language\nGot:\n%s", markdown)
+	}
+}
+
 func TestRobustCodeBlock_GutterStripping(t *testing.T) {
 	tests := []struct {
 		name        string
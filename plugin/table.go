@@ -4,8 +4,8 @@ import (
 	"regexp"
 	"strings"
 
-	md "github.com/firecrawl/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
 	"golang.org/x/net/html"
 )
 
@@ -47,6 +47,65 @@ func TableCompat() md.Plugin {
 	}
 }
 
+// TableOptions configures the `Table` plugin.
+type TableOptions struct {
+	// SpanCellBehavior decides how the cells that are covered
+	// by a `colspan` or `rowspan` are filled:
+	//   - "empty" (default) the cells contain the SpanPlaceholder
+	//   - "mirror" the content of the spanning cell is repeated
+	SpanCellBehavior string
+
+	// SpanPlaceholder is the content of the covered cells
+	// for the "empty" SpanCellBehavior. Defaults to an empty cell.
+	SpanPlaceholder string
+
+	// ComplexTableMode decides how tables are converted that can't be
+	// expressed with a pipe table: tables with nested tables, lists or code
+	// blocks inside of cells and tables with more than one header row.
+	//   - "markdown" (default) the cells are flattened into a pipe table
+	//   - "html" the table is kept as sanitized html
+	//   - "grid" a Pandoc grid table that supports block content in cells
+	//   - "list" every row is a list item with "Header: Value" lines
+	ComplexTableMode string
+
+	// AlignmentClasses maps class names to the alignment ("left", "right" or "center")
+	// of the column. Defaults to the classes of common css frameworks like "text-right".
+	AlignmentClasses map[string]string
+
+	// HeaderRows decides how a `<thead>` with multiple rows is converted:
+	//   - "first" (default) only the first row is the header
+	//   - "merge" the rows are merged into labels like "Parent / Child"
+	HeaderRows string
+
+	// FooterPosition decides where the rows of the `<tfoot>` are placed:
+	//   - "inline" (default) in the order of the html source
+	//   - "end" after the rows of the body
+	FooterPosition string
+
+	// FooterSeparator is the content of the cells of an additional
+	// row between the body and the footer, for example "---".
+	// It is only used for the "end" FooterPosition.
+	FooterSeparator string
+
+	// CaptionPosition decides how the `<caption>` is converted:
+	//   - "after" (default) the content is moved after the table
+	//   - "above" an emphasized line above the table
+	//   - "below" an emphasized line below the table
+	CaptionPosition string
+
+	// EmphasizeRowHeaders makes the content of `<th>` cells in
+	// the body (the row headers) bold.
+	EmphasizeRowHeaders bool
+
+	// PadColumns pads the cells of pipe tables, so that the
+	// pipe characters of all rows are aligned.
+	PadColumns bool
+
+	// MaxColumnWidth is the display width of a column above which
+	// the column is not padded. Zero means that there is no limit.
+	MaxColumnWidth int
+}
+
 // Table converts a html table (using hyphens and pipe characters) to a
 // visuall representation in markdown.
 //
@@ -54,8 +113,40 @@ func TableCompat() md.Plugin {
 // Only use this Plugin in an environment that has extendeded the normal syntax,
 // like GitHub's Flavored Markdown.
 func Table() md.Plugin {
+	return TableWithOptions(nil)
+}
+
+// TableWithOptions is the same as `Table` but the conversion
+// can be configured with the TableOptions.
+func TableWithOptions(options *TableOptions) md.Plugin {
+	var tableOpt TableOptions
+	if options != nil {
+		tableOpt = *options
+	}
+	if tableOpt.SpanCellBehavior == "" {
+		tableOpt.SpanCellBehavior = "empty"
+	}
+	if tableOpt.ComplexTableMode == "" {
+		tableOpt.ComplexTableMode = "markdown"
+	}
+	if tableOpt.AlignmentClasses == nil {
+		tableOpt.AlignmentClasses = defaultAlignmentClasses
+	}
+	if tableOpt.HeaderRows == "" {
+		tableOpt.HeaderRows = "first"
+	}
+	if tableOpt.FooterPosition == "" {
+		tableOpt.FooterPosition = "inline"
+	}
+	if tableOpt.CaptionPosition == "" {
+		tableOpt.CaptionPosition = "after"
+	}
+
 	return func(c *md.Converter) []md.Rule {
 		c.Before(func(selec *goquery.Selection) {
+			if tableOpt.CaptionPosition != "after" {
+				return
+			}
 			selec.Find("caption").Each(func(i int, s *goquery.Selection) {
 				parent := s.Parent()
 				if !parent.Is("table") {
@@ -71,61 +162,74 @@ func Table() md.Plugin {
 			{
 				Filter: []string{"table"},
 				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
-					noHeader := selec.Find("thead").Length() == 0 && selec.Find("th").Length() == 0
-					if noHeader {
-						var maxCount int
-						selec.Find("tr").Each(func(i int, s *goquery.Selection) {
-							count := s.Children().Length()
-							if count > maxCount {
-								maxCount = count
-							}
-						})
-
-						// add an empty header, so that the table is recognized.
-						header := "|" + strings.Repeat("     |", maxCount)
-						divider := "|" + strings.Repeat(" --- |", maxCount)
-
-						content = header + "\n" + divider + content
+					grid := newTableGrid(selec, &tableOpt)
+					if len(grid.rows) == 0 {
+						return md.String("")
 					}
 
-					content = "\n\n" + content + "\n\n"
-					return &content
+					var text string
+					if !grid.isComplex() {
+						text = grid.markdown()
+					} else {
+						switch tableOpt.ComplexTableMode {
+						case "html":
+							// the caption is part of the html
+							return md.String("\n\n" + tableHTML(selec, opt) + "\n\n")
+						case "grid":
+							text = grid.gridMarkdown()
+						case "list":
+							text = grid.listMarkdown(opt)
+						default:
+							text = grid.markdown()
+						}
+					}
+
+					caption := selec.ChildrenFiltered("caption").AttrOr(attrTableCaption, "")
+					if caption != "" {
+						caption = opt.EmDelimiter + caption + opt.EmDelimiter
+						switch tableOpt.CaptionPosition {
+						case "above":
+							text = caption + "\n\n" + text
+						case "below":
+							text = text + "\n\n" + caption
+						}
+					}
+
+					return md.String("\n\n" + text + "\n\n")
 				},
 			},
 			{ // TableCell
 				Filter: []string{"th", "td"},
 				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
-					return md.String(getCellContent(content, selec))
+					content = strings.TrimSpace(content)
+
+					row := selec.Parent()
+					isRowHeader := selec.Is("th") && !row.Parent().Is("thead") && !isHeadingRow(row)
+					if tableOpt.EmphasizeRowHeaders && isRowHeader && content != "" {
+						content = opt.StrongDelimiter + content + opt.StrongDelimiter
+					}
+
+					// The table rule places the cells into the grid.
+					selec.SetAttr(attrTableCell, content)
+					return md.String(content)
 				},
 			},
-			{ // TableRow
-				Filter: []string{"tr"},
+			{
+				Filter: []string{"caption"},
 				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
-					var borderBuilder strings.Builder
-
-					if isHeadingRow(selec) {
-						selec.Children().Each(func(i int, s *goquery.Selection) {
-							border := "---"
-							if align, ok := s.Attr("align"); ok {
-								switch align {
-								case "left":
-									border = ":--"
-								case "right":
-									border = "--:"
-								case "center":
-									border = ":-:"
-								}
-							}
-
-							borderBuilder.WriteString(getCellContent(border, s))
-						})
+					if !selec.Parent().Is("table") {
+						return nil
 					}
 
-					text := "\n" + content
-					if borderBuilder.Len() > 0 {
-						text += "\n" + borderBuilder.String()
-					}
-					return &text
+					// The table rule places the caption above or below the table.
+					selec.SetAttr(attrTableCaption, strings.Join(strings.Fields(content), " "))
+					return md.String("")
+				},
+			},
+			{ // TableRow
+				Filter: []string{"tr"},
+				Replacement: func(content string, selec *goquery.Selection, opt *md.Options) *string {
+					return md.String(content)
 				},
 			},
 		}
@@ -180,12 +284,8 @@ func isHeadingRow(s *goquery.Selection) bool {
 	return false
 }
 func isFirstTbody(s *goquery.Selection) bool {
-	firstSibling := s.Siblings().Eq(0) // TODO: previousSibling
-	if s.Is("tbody") && firstSibling.Length() == 0 {
-		return true
-	}
-
-	return false
+	// the colgroup and caption are placed before the rows
+	return s.Is("tbody") && s.PrevAll().Not("colgroup, col, caption").Length() == 0
 }
 
 var newLineRe = regexp.MustCompile(`(\r?\n)+`)
@@ -194,29 +294,53 @@ func getCellContent(content string, s *goquery.Selection) string {
 	content = strings.TrimSpace(content)
 	if s.Find("table").Length() == 0 {
 		// nested tables not found
+		content = codeBlocksToHTML(content)
 		content = newLineRe.ReplaceAllString(content, "<br>")
 	}
+	return content
+}
 
-	// Optimize: Check if this is the first element child by comparing node pointers directly
-	// instead of linear search through all children
-	parent := s.Parent()
-	isFirst := false
-	if len(s.Nodes) > 0 && len(parent.Nodes) > 0 {
-		parentNode := parent.Nodes[0]
-		sNode := s.Nodes[0]
-
-		// Find the first element child (skip text nodes)
-		for child := parentNode.FirstChild; child != nil; child = child.NextSibling {
-			if child.Type == html.ElementNode {
-				isFirst = (child == sNode)
-				break
+// cellCodeReplacer escapes the code for a `<code>` element inside of a cell.
+var cellCodeReplacer = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "|", `\|`, "\n", "<br>")
+
+// codeBlocksToHTML replaces the fenced code blocks with `<code>` elements,
+// since a code block can't be inside of a cell and the `<br>` between
+// the lines would be displayed as text inside of inline code.
+func codeBlocksToHTML(content string) string {
+	lines := strings.Split(content, "\n")
+
+	var result []string
+	for i := 0; i < len(lines); i++ {
+		fence := codeFence(lines[i])
+		end := -1
+		if fence != "" {
+			for j := i + 1; j < len(lines); j++ {
+				if strings.TrimSpace(lines[j]) == fence {
+					end = j
+					break
+				}
 			}
 		}
+		if end == -1 {
+			result = append(result, lines[i])
+			continue
+		}
+
+		code := cellCodeReplacer.Replace(strings.Join(lines[i+1:end], "\n"))
+		result = append(result, "<code>"+code+"</code>")
+		i = end
 	}
+	return strings.Join(result, "\n")
+}
 
-	prefix := " "
-	if isFirst {
-		prefix = "| "
+// codeFence returns the fence that opens a fenced code block on the line.
+func codeFence(line string) string {
+	trimmed := strings.TrimSpace(line)
+	for _, char := range []string{"`", "~"} {
+		fence := trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, char))]
+		if len(fence) >= 3 {
+			return fence
+		}
 	}
-	return prefix + content + " |"
+	return ""
 }
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// defaultAlignmentClasses are the alignment classes of
+// Bootstrap, Tailwind, Bulma, Foundation and MediaWiki.
+var defaultAlignmentClasses = map[string]string{
+	"text-left":          "left",
+	"text-start":         "left",
+	"text-right":         "right",
+	"text-end":           "right",
+	"text-center":        "center",
+	"has-text-left":      "left",
+	"has-text-right":     "right",
+	"has-text-centered":  "center",
+	"align-left":         "left",
+	"align-right":        "right",
+	"align-center":       "center",
+	"text-align-left":    "left",
+	"text-align-right":   "right",
+	"text-align-center":  "center",
+	"wikitable-right":    "right",
+	"wikitable-centered": "center",
+}
+
+var textAlignR = regexp.MustCompile(`(?i)(?:^|;)\s*text-align\s*:\s*([a-z-]+)`)
+
+// elementAlignment returns the alignment that is specified on the element
+// with the `align` attribute, the `text-align` style or a class.
+func elementAlignment(s *goquery.Selection, classes map[string]string) string {
+	if align := normalizeAlignment(s.AttrOr("align", "")); align != "" {
+		return align
+	}
+	if m := textAlignR.FindStringSubmatch(s.AttrOr("style", "")); m != nil {
+		if align := normalizeAlignment(m[1]); align != "" {
+			return align
+		}
+	}
+	for _, class := range strings.Fields(s.AttrOr("class", "")) {
+		if align, ok := classes[class]; ok {
+			return align
+		}
+	}
+	return ""
+}
+
+func normalizeAlignment(align string) string {
+	switch strings.ToLower(strings.TrimSpace(align)) {
+	case "left", "start":
+		return "left"
+	case "right", "end":
+		return "right"
+	case "center", "middle":
+		return "center"
+	}
+	return ""
+}
+
+// alignments returns the alignment of every column. It is taken from (in that order)
+// the header cell, the `<col>` in the `<colgroup>` or the majority of the body cells.
+func (g *tableGrid) alignments() []string {
+	aligns := make([]string, g.width)
+	classes := g.opt.AlignmentClasses
+
+	if isHeadingRow(g.rows[0].selec) {
+		headerCount := 1
+		if g.opt.HeaderRows == "merge" {
+			headerCount = g.headerCount()
+		}
+		// the lowest header row is the most specific
+		for r := headerCount - 1; r >= 0; r-- {
+			for col, cell := range g.rows[r].cells {
+				if aligns[col] == "" && cell.selec != nil && !cell.spanned {
+					aligns[col] = elementAlignment(cell.selec, classes)
+				}
+			}
+		}
+	}
+
+	// the columns of the colgroup, a colgroup without cols can also have a span
+	var col int
+	g.table.ChildrenFiltered("colgroup").Each(func(i int, group *goquery.Selection) {
+		groupAlign := elementAlignment(group, classes)
+
+		cols := group.ChildrenFiltered("col")
+		if cols.Length() == 0 {
+			col = g.fillAlignment(aligns, col, spanAttr(group, "span", 1), groupAlign)
+			return
+		}
+		cols.Each(func(i int, s *goquery.Selection) {
+			align := elementAlignment(s, classes)
+			if align == "" {
+				align = groupAlign
+			}
+			col = g.fillAlignment(aligns, col, spanAttr(s, "span", 1), align)
+		})
+	})
+
+	for col := range aligns {
+		if aligns[col] == "" {
+			aligns[col] = g.bodyAlignment(col)
+		}
+	}
+	return aligns
+}
+
+// fillAlignment sets the alignment for the columns that are not yet aligned
+// and returns the column after the span.
+func (g *tableGrid) fillAlignment(aligns []string, col, span int, align string) int {
+	for i := col; i < col+span && i < len(aligns); i++ {
+		if aligns[i] == "" {
+			aligns[i] = align
+		}
+	}
+	return col + span
+}
+
+// bodyAlignment returns the alignment that more than
+// half of the cells in the column have in common.
+func (g *tableGrid) bodyAlignment(col int) string {
+	counts := make(map[string]int)
+	var total int
+	for _, row := range g.rows[g.headerCount():] {
+		cell := row.cells[col]
+		if cell.selec == nil || cell.spanned || cell.content == "" {
+			continue
+		}
+		total++
+
+		align := elementAlignment(cell.selec, g.opt.AlignmentClasses)
+		if align == "" {
+			align = elementAlignment(row.selec, g.opt.AlignmentClasses)
+		}
+		if align != "" {
+			counts[align]++
+		}
+	}
+
+	for align, count := range counts {
+		if count*2 > total {
+			return align
+		}
+	}
+	return ""
+}
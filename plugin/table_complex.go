@@ -0,0 +1,295 @@
+package plugin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+	"golang.org/x/net/html"
+)
+
+// headerCount returns the number of rows at the top of the grid that are header rows.
+func (g *tableGrid) headerCount() int {
+	var count int
+	for _, row := range g.rows {
+		if !row.selec.Parent().Is("thead") {
+			break
+		}
+		count++
+	}
+	if count == 0 && isHeadingRow(g.rows[0].selec) {
+		count = 1
+	}
+	return count
+}
+
+// isComplex reports whether the table can't be expressed as a pipe table
+// without loosing information.
+func (g *tableGrid) isComplex() bool {
+	if g.headerCount() > 1 && g.opt.HeaderRows != "merge" {
+		return true
+	}
+	for _, row := range g.rows {
+		for _, cell := range row.cells {
+			if cell.selec == nil || cell.spanned {
+				continue
+			}
+			if cell.selec.Find("table, ul, ol, pre, dl, blockquote").Length() > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// blockText is like text but keeps the newlines of the block content.
+func (g *tableGrid) blockText(cell tableCell) string {
+	if cell.spanned && g.opt.SpanCellBehavior != "mirror" {
+		return g.opt.SpanPlaceholder
+	}
+	return cell.content
+}
+
+// headerLabels returns the label for every column. The labels of
+// multiple header rows are combined to "Parent / Child".
+func (g *tableGrid) headerLabels(headerCount int) []string {
+	labels := make([]string, g.width)
+	for col := range labels {
+		var parts []string
+		for _, row := range g.rows[:headerCount] {
+			part := strings.Join(strings.Fields(row.cells[col].content), " ")
+			if part == "" || (len(parts) > 0 && parts[len(parts)-1] == part) {
+				continue
+			}
+			parts = append(parts, part)
+		}
+		labels[col] = strings.Join(parts, " / ")
+	}
+	return labels
+}
+
+// gridMarkdown returns the table as a Pandoc grid table, where
+// the cells can contain block content like lists and code blocks.
+func (g *tableGrid) gridMarkdown() string {
+	headerCount := g.headerCount()
+
+	cells := make([][][]string, len(g.rows))
+	widths := make([]int, g.width)
+	for i := range widths {
+		widths[i] = 3
+	}
+	for r, row := range g.rows {
+		cells[r] = make([][]string, g.width)
+		for col, cell := range row.cells {
+			lines := strings.Split(g.blockText(cell), "\n")
+			cells[r][col] = lines
+			for _, line := range lines {
+				if w := textWidth(line); w > widths[col] {
+					widths[col] = w
+				}
+			}
+		}
+	}
+
+	separator := func(char string, aligns []string) string {
+		var b strings.Builder
+		b.WriteString("+")
+		for col, w := range widths {
+			border := []byte(strings.Repeat(char, w+2))
+			if aligns != nil {
+				switch aligns[col] {
+				case "left":
+					border[0] = ':'
+				case "right":
+					border[len(border)-1] = ':'
+				case "center":
+					border[0], border[len(border)-1] = ':', ':'
+				}
+			}
+			b.WriteString(string(border) + "+")
+		}
+		return b.String()
+	}
+
+	var b strings.Builder
+	b.WriteString(separator("-", nil))
+	for r := range cells {
+		var height int
+		for _, lines := range cells[r] {
+			if len(lines) > height {
+				height = len(lines)
+			}
+		}
+
+		for i := 0; i < height; i++ {
+			b.WriteString("\n|")
+			for col, lines := range cells[r] {
+				var line string
+				if i < len(lines) {
+					line = lines[i]
+				}
+				b.WriteString(" " + line + strings.Repeat(" ", widths[col]-textWidth(line)) + " |")
+			}
+		}
+
+		if r == headerCount-1 {
+			// the alignment is placed on the separator after the header
+			b.WriteString("\n" + separator("=", g.alignments()))
+		} else {
+			b.WriteString("\n" + separator("-", nil))
+		}
+	}
+	return b.String()
+}
+
+// listMarkdown linearizes the table into a list where every row
+// is a record of "Header: Value" lines.
+func (g *tableGrid) listMarkdown(opt *md.Options) string {
+	headerCount := g.headerCount()
+	labels := g.headerLabels(headerCount)
+	indent := strings.Repeat(" ", len(opt.BulletListMarker)+1)
+
+	var items []string
+	for _, row := range g.rows[headerCount:] {
+		var fields []string
+		for col, cell := range row.cells {
+			value := g.blockText(cell)
+			if strings.TrimSpace(value) == "" {
+				continue
+			}
+
+			label := labels[col]
+			if label == "" {
+				label = "Column " + strconv.Itoa(col+1)
+			}
+
+			if strings.Contains(value, "\n") {
+				fields = append(fields, label+":\n\n"+value)
+			} else {
+				fields = append(fields, label+": "+value)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		// the fields are separate lines of the paragraph, but
+		// block content needs to be separated by a blank line
+		item := fields[0]
+		for i := 1; i < len(fields); i++ {
+			if strings.Contains(fields[i-1], "\n") || strings.Contains(fields[i], "\n") {
+				item += "\n\n" + fields[i]
+			} else {
+				item += md.HardLineBreak(opt) + fields[i]
+			}
+		}
+		items = append(items, opt.BulletListMarker+" "+indentLines(item, indent, true))
+	}
+	return strings.Join(items, "\n")
+}
+
+// tableRemoveElements are removed including their content.
+var tableRemoveElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true,
+	"noscript": true, "template": true, "form": true, "input": true, "button": true,
+	"select": true, "textarea": true, "svg": true, "math": true,
+}
+
+// tableAllowedElements are kept, every other element is replaced by its content.
+var tableAllowedElements = map[string]bool{
+	"table": true, "caption": true, "colgroup": true, "col": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true, "th": true, "td": true,
+	"a": true, "b": true, "strong": true, "i": true, "em": true, "code": true,
+	"pre": true, "br": true, "p": true, "ul": true, "ol": true, "li": true,
+	"dl": true, "dt": true, "dd": true, "sub": true, "sup": true, "img": true,
+	"blockquote": true, "del": true, "s": true, "hr": true,
+}
+
+var tableAllowedAttributes = map[string]bool{
+	"colspan": true, "rowspan": true, "scope": true, "headers": true, "align": true,
+	"href": true, "src": true, "alt": true, "title": true, "start": true,
+}
+
+var blankLinesR = regexp.MustCompile(`\n\s*\n`)
+
+// tableHTML returns the table as sanitized html. Only the structure, basic
+// formatting and safe attributes are kept. The urls are made absolute and
+// transformed like the urls of the links and images.
+func tableHTML(selec *goquery.Selection, opt *md.Options) string {
+	table := selec.Clone().Get(0)
+	sanitizeTableNode(table, opt)
+
+	var b strings.Builder
+	if err := html.Render(&b, table); err != nil {
+		return ""
+	}
+
+	// A blank line would end the html block in markdown.
+	return blankLinesR.ReplaceAllString(b.String(), "\n")
+}
+
+func sanitizeTableNode(n *html.Node, opt *md.Options) {
+	var attrs []html.Attribute
+	for _, attr := range n.Attr {
+		if !tableAllowedAttributes[attr.Key] {
+			continue
+		}
+		if attr.Key == "href" || attr.Key == "src" {
+			attr.Val = tableURL(n, attr, opt)
+			if attr.Val == "" {
+				continue
+			}
+		}
+		attrs = append(attrs, attr)
+	}
+	n.Attr = attrs
+
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+
+		switch c.Type {
+		case html.ElementNode:
+			if tableRemoveElements[c.Data] {
+				n.RemoveChild(c)
+				break
+			}
+
+			sanitizeTableNode(c, opt)
+			if !tableAllowedElements[c.Data] {
+				// unwrap the element
+				for gc := c.FirstChild; gc != nil; gc = c.FirstChild {
+					c.RemoveChild(gc)
+					n.InsertBefore(gc, c)
+				}
+				n.RemoveChild(c)
+			}
+		case html.CommentNode:
+			n.RemoveChild(c)
+		}
+
+		c = next
+	}
+}
+
+// tableURL returns the url of the href or src attribute, the same way as
+// the rules for the links and images. An empty string removes the attribute.
+func tableURL(n *html.Node, attr html.Attribute, opt *md.Options) string {
+	kind := md.URLKindAnchor
+	if attr.Key == "src" {
+		kind = md.URLKindImage
+	}
+
+	selec := goquery.NewDocumentFromNode(n).Selection
+	rawURL := strings.TrimSpace(attr.Val)
+	if kind == md.URLKindAnchor && strings.HasPrefix(rawURL, "#") {
+		// an in-page link
+		return rawURL
+	}
+	rawURL = md.TransformURL(selec, rawURL, kind, opt)
+	if rawURL != "" {
+		rawURL = md.AssetURL(selec, rawURL, opt)
+	}
+	return rawURL
+}
@@ -0,0 +1,269 @@
+package plugin
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const (
+	attrTableCell    = "data-converter-table-cell"
+	attrTableCaption = "data-converter-table-caption"
+)
+
+// maxTableSpan limits the colspan and rowspan, like browsers do.
+const maxTableSpan = 1000
+
+// maxTableGridCells limits the size of the grid (rows × columns). Above it,
+// the colspan and rowspan are ignored and the cells are kept in source order,
+// so that a small table with big spans can't produce huge output.
+const maxTableGridCells = 100000
+
+type tableCell struct {
+	content string
+	selec   *goquery.Selection
+
+	// spanned is true if the cell is covered by
+	// the colspan or rowspan of another cell.
+	spanned bool
+}
+
+type tableRow struct {
+	selec *goquery.Selection
+	cells []tableCell
+}
+
+// tableGrid is the logical grid of a table, after the
+// colspan and rowspan of the cells are expanded.
+type tableGrid struct {
+	opt   *TableOptions
+	table *goquery.Selection
+	rows  []tableRow
+	width int
+}
+
+// newTableGrid builds the grid from the rows of the table
+// (but not the rows of nested tables).
+func newTableGrid(table *goquery.Selection, opt *TableOptions) *tableGrid {
+	grid := &tableGrid{opt: opt, table: table}
+
+	var rows, footer []*goquery.Selection
+	table.Find("tr").Each(func(i int, tr *goquery.Selection) {
+		if !tr.Closest("table").IsSelection(table) {
+			return
+		}
+		if opt.FooterPosition == "end" && tr.Parent().Is("tfoot") {
+			footer = append(footer, tr)
+			return
+		}
+		rows = append(rows, tr)
+	})
+	rows = append(rows, footer...)
+
+	if !grid.expand(rows, true) {
+		grid.expand(rows, false)
+	}
+	return grid
+}
+
+// expand fills the grid with the cells of the rows. With `spans` the colspan
+// and rowspan are expanded. It returns false if the grid would get too big.
+func (g *tableGrid) expand(rows []*goquery.Selection, spans bool) bool {
+	g.rows = make([]tableRow, len(rows))
+	g.width = 0
+
+	// the index after the last row of the section (thead, tbody, tfoot) of every row
+	sectionEnds := make([]int, len(rows))
+	for r := len(rows) - 1; r >= 0; r-- {
+		sectionEnds[r] = r + 1
+		if r+1 < len(rows) && rows[r+1].Parent().Get(0) == rows[r].Parent().Get(0) {
+			sectionEnds[r] = sectionEnds[r+1]
+		}
+	}
+
+	for r, tr := range rows {
+		g.rows[r].selec = tr
+
+		col := 0
+		ok := true
+		tr.ChildrenFiltered("th, td").EachWithBreak(func(i int, cell *goquery.Selection) bool {
+			// skip the columns that are occupied by a rowspan from above
+			for col < len(g.rows[r].cells) && g.rows[r].cells[col].selec != nil {
+				col++
+			}
+
+			colspan, rowspan := 1, 1
+			if spans {
+				colspan = spanAttr(cell, "colspan", 1)
+				rowspan = spanAttr(cell, "rowspan", 1)
+			}
+			// a rowspan of zero extends to the end of the section (thead, tbody, tfoot)
+			if end := sectionEnds[r]; rowspan == 0 || r+rowspan > end {
+				rowspan = end - r
+			}
+
+			// every row is padded to the widest row
+			if width := col + colspan; width > g.width {
+				g.width = width
+			}
+			if spans && g.width*len(rows) > maxTableGridCells {
+				ok = false
+				return false
+			}
+
+			content := cell.AttrOr(attrTableCell, "")
+			for y := 0; y < rowspan; y++ {
+				for x := 0; x < colspan; x++ {
+					g.set(r+y, col+x, tableCell{
+						content: content,
+						selec:   cell,
+						spanned: x != 0 || y != 0,
+					})
+				}
+			}
+			col += colspan
+			return true
+		})
+		if !ok {
+			return false
+		}
+	}
+
+	// pad the rows, so that every row has the same number of cells
+	for r := range g.rows {
+		for len(g.rows[r].cells) < g.width {
+			g.rows[r].cells = append(g.rows[r].cells, tableCell{})
+		}
+	}
+	return true
+}
+
+func (g *tableGrid) set(r, col int, cell tableCell) {
+	row := &g.rows[r]
+	for len(row.cells) <= col {
+		row.cells = append(row.cells, tableCell{})
+	}
+	if row.cells[col].selec != nil {
+		// overlapping cells: the first one wins
+		return
+	}
+	row.cells[col] = cell
+}
+
+// text returns the markdown for the cell in the grid.
+func (g *tableGrid) text(cell tableCell) string {
+	if cell.spanned && g.opt.SpanCellBehavior != "mirror" {
+		return g.opt.SpanPlaceholder
+	}
+	if cell.selec == nil {
+		return ""
+	}
+	return getCellContent(cell.content, cell.selec)
+}
+
+func (g *tableGrid) markdown() string {
+	var header []string
+	body := g.rows
+	switch {
+	case !isHeadingRow(g.rows[0].selec):
+		// add an empty header, so that the table is recognized.
+		header = repeatString("   ", g.width)
+	case g.opt.HeaderRows == "merge":
+		headerCount := g.headerCount()
+		header = g.headerLabels(headerCount)
+		body = g.rows[headerCount:]
+	default:
+		header = g.rowTexts(g.rows[0])
+		body = g.rows[1:]
+	}
+
+	var rows [][]string
+	for i, row := range body {
+		isFirstFooter := row.selec.Parent().Is("tfoot") && (i == 0 || !body[i-1].selec.Parent().Is("tfoot"))
+		if isFirstFooter && g.opt.FooterPosition == "end" && g.opt.FooterSeparator != "" {
+			rows = append(rows, repeatString(g.opt.FooterSeparator, g.width))
+		}
+		rows = append(rows, g.rowTexts(row))
+	}
+
+	widths := g.columnWidths(append([][]string{header}, rows...))
+	aligns := g.alignments()
+
+	var b strings.Builder
+	writeCells(&b, header, aligns, widths)
+	b.WriteString("\n")
+	writeDivider(&b, aligns, widths)
+	for _, row := range rows {
+		b.WriteString("\n")
+		writeCells(&b, row, aligns, widths)
+	}
+	return b.String()
+}
+
+func (g *tableGrid) rowTexts(row tableRow) []string {
+	texts := make([]string, len(row.cells))
+	for i, cell := range row.cells {
+		texts[i] = g.text(cell)
+	}
+	return texts
+}
+
+func repeatString(s string, count int) []string {
+	list := make([]string, count)
+	for i := range list {
+		list[i] = s
+	}
+	return list
+}
+
+// writeCells writes a row of the pipe table. The cells
+// are padded to the width of the column.
+func writeCells(b *strings.Builder, texts []string, aligns []string, widths []int) {
+	b.WriteString("|")
+	for col, text := range texts {
+		pad := padding(text, widths[col])
+		switch aligns[col] {
+		case "right":
+			text = pad + text
+		case "center":
+			half := len(pad) / 2
+			text = pad[:half] + text + pad[half:]
+		default:
+			text += pad
+		}
+		b.WriteString(" " + text + " |")
+	}
+}
+
+func writeDivider(b *strings.Builder, aligns []string, widths []int) {
+	b.WriteString("|")
+	for col, align := range aligns {
+		border := []byte(strings.Repeat("-", maxInt(widths[col], 3)))
+		switch align {
+		case "left":
+			border[0] = ':'
+		case "right":
+			border[len(border)-1] = ':'
+		case "center":
+			border[0], border[len(border)-1] = ':', ':'
+		}
+		b.WriteString(" " + string(border) + " |")
+	}
+}
+
+// spanAttr parses the colspan or rowspan attribute of a cell.
+func spanAttr(cell *goquery.Selection, name string, fallback int) int {
+	val, ok := cell.Attr(name)
+	if !ok {
+		return fallback
+	}
+	span, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil || span < 0 || (span == 0 && name == "colspan") {
+		return fallback
+	}
+	if span > maxTableSpan {
+		return maxTableSpan
+	}
+	return span
+}
@@ -0,0 +1,30 @@
+package plugin
+
+import "testing"
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"a \\| b", 6},
+		{"a<br>b", 6},
+		{"日本語", 6},
+		{"ｈｅｌｌｏ", 10},
+		{"한국", 4},
+		{"café", 4},
+		{"café", 4},
+		{"🎉", 2},
+		{"👍🏽", 2},
+		{"👨‍👩‍👧", 2},
+		{"❤️", 2},
+		{"✓", 1},
+	}
+	for _, test := range tests {
+		if width := textWidth(test.text); width != test.width {
+			t.Errorf("expected %q to have the width %d but got %d", test.text, test.width, width)
+		}
+	}
+}
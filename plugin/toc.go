@@ -0,0 +1,127 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	md "github.com/firecrawl/html-to-markdown"
+	"github.com/firecrawl/html-to-markdown/escape"
+	"golang.org/x/net/html"
+)
+
+const attrTOC = "data-converter-toc"
+
+// existingTOC are the elements of a table of contents
+// that is already part of the page.
+var existingTOC = strings.Join([]string{
+	"nav.toc",
+	"#toc",
+	".toc",
+	"#table-of-contents",
+	".table-of-contents",
+	"nav#TableOfContents",
+	".toctree-wrapper",
+	"[role='doc-toc']",
+}, ", ")
+
+// TOCOptions configures the `TableOfContents` plugin.
+type TOCOptions struct {
+	// MinLevel is the level of the highest heading that is included.
+	// default: 1
+	MinLevel int
+
+	// MaxLevel is the level of the lowest heading that is included.
+	// default: 6
+	MaxLevel int
+
+	// ReplaceExisting removes a table of contents that is already part
+	// of the page (e.g. `nav.toc` or `#toc`) instead of duplicating it.
+	ReplaceExisting bool
+}
+
+// TableOfContents adds a nested list with links to the headings at the
+// beginning of the document. The links point to the ids of the headings
+// that are kept with `HeadingIDStyle` or else to the slugs that GitHub generates.
+func TableOfContents(options *TOCOptions) md.Plugin {
+	var tocOpt TOCOptions
+	if options != nil {
+		tocOpt = *options
+	}
+	if tocOpt.MinLevel < 1 || tocOpt.MinLevel > 6 {
+		tocOpt.MinLevel = 1
+	}
+	if tocOpt.MaxLevel < tocOpt.MinLevel || tocOpt.MaxLevel > 6 {
+		tocOpt.MaxLevel = 6
+	}
+
+	return func(c *md.Converter) []md.Rule {
+		c.Before(func(selec *goquery.Selection) {
+			if tocOpt.ReplaceExisting {
+				selec.Find(existingTOC).Remove()
+			}
+
+			// the header is added in the order of the elements, so the table
+			// of contents is placed after a front matter
+			body := selec.Find("body")
+			if body.Length() == 0 {
+				body = selec
+			}
+			body.AppendNodes(&html.Node{
+				Type: html.ElementNode,
+				Data: "div",
+				Attr: []html.Attribute{{Key: attrTOC, Val: "true"}},
+			})
+		})
+
+		return []md.Rule{
+			{
+				Filter: []string{"div"},
+				AdvancedReplacement: func(content string, selec *goquery.Selection, opt *md.Options) (md.AdvancedResult, bool) {
+					if _, ok := selec.Attr(attrTOC); !ok {
+						return md.AdvancedResult{}, true
+					}
+
+					root := selec.Parents().Last()
+					toc := tableOfContents(root, &tocOpt, opt)
+					return md.AdvancedResult{Header: toc}, false
+				},
+			},
+		}
+	}
+}
+
+// tableOfContents returns the nested list of the headings.
+func tableOfContents(root *goquery.Selection, tocOpt *TOCOptions, opt *md.Options) string {
+	var b strings.Builder
+
+	// the levels of the parent entries, to support skipped levels like h2 -> h4
+	var parents []int
+	root.Find("h1, h2, h3, h4, h5, h6").Each(func(i int, s *goquery.Selection) {
+		level, _ := md.HeadingLevel(s)
+		if level < tocOpt.MinLevel || level > tocOpt.MaxLevel {
+			return
+		}
+		if s.Closest(existingTOC).Length() > 0 {
+			return
+		}
+
+		text := strings.Join(strings.Fields(s.Text()), " ")
+		if text == "" {
+			return
+		}
+		if opt.EscapeMode == "basic" {
+			text = escape.MarkdownCharacters(text)
+		}
+
+		anchor := md.HeadingAnchor(s, opt)
+
+		for len(parents) > 0 && parents[len(parents)-1] >= level {
+			parents = parents[:len(parents)-1]
+		}
+		b.WriteString(strings.Repeat("  ", len(parents)))
+		b.WriteString(opt.BulletListMarker + " [" + text + "](#" + anchor + ")\n")
+		parents = append(parents, level)
+	})
+
+	return b.String()
+}
@@ -46,6 +46,226 @@ func TestPlugins(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:                 "table_span",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"empty": {
+					Plugins: []md.Plugin{
+						plugin.Table(),
+					},
+				},
+				"placeholder": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{SpanPlaceholder: "〃"}),
+					},
+				},
+				"mirror": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{SpanCellBehavior: "mirror"}),
+					},
+				},
+			},
+		},
+		{
+			Name:                 "table_complex",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"markdown": {
+					Plugins: []md.Plugin{
+						plugin.Table(),
+					},
+				},
+				"html": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{ComplexTableMode: "html"}),
+					},
+				},
+				"html_transform": {
+					Options: &md.Options{
+						URLTransformers: []md.URLTransformer{md.RemoveTrackingParameters()},
+					},
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{ComplexTableMode: "html"}),
+					},
+				},
+				"grid": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{ComplexTableMode: "grid"}),
+					},
+				},
+				"list": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{ComplexTableMode: "list"}),
+					},
+				},
+			},
+		},
+		{
+			Name: "table_align",
+			Variations: map[string]Variation{
+				"default": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{
+							AlignmentClasses: map[string]string{"num": "right", "text-center": "center"},
+						}),
+					},
+				},
+			},
+		},
+		{
+			Name:                 "table_sections",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"default": {
+					Plugins: []md.Plugin{
+						plugin.Table(),
+					},
+				},
+				"above": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{
+							HeaderRows:          "merge",
+							FooterPosition:      "end",
+							CaptionPosition:     "above",
+							EmphasizeRowHeaders: true,
+						}),
+					},
+				},
+				"below": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{
+							HeaderRows:      "merge",
+							FooterPosition:  "end",
+							FooterSeparator: "—",
+							CaptionPosition: "below",
+						}),
+					},
+				},
+			},
+		},
+		{
+			Name: "table_pretty",
+			Variations: map[string]Variation{
+				"padded": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{PadColumns: true}),
+					},
+				},
+				"maxwidth": {
+					Plugins: []md.Plugin{
+						plugin.TableWithOptions(&plugin.TableOptions{PadColumns: true, MaxColumnWidth: 12}),
+					},
+				},
+			},
+		},
+		{
+			Name:                 "definition_list",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"extra": {
+					Plugins: []md.Plugin{
+						plugin.DefinitionList(nil),
+					},
+				},
+				"bold": {
+					Plugins: []md.Plugin{
+						plugin.DefinitionList(&plugin.DefinitionListOptions{Style: "bold"}),
+					},
+				},
+				"list": {
+					Plugins: []md.Plugin{
+						plugin.DefinitionList(&plugin.DefinitionListOptions{Style: "list"}),
+					},
+				},
+			},
+		},
+		{
+			Name: "footnote",
+			Variations: map[string]Variation{
+				"default": {
+					Plugins: []md.Plugin{
+						plugin.Footnotes(),
+					},
+				},
+			},
+		},
+		{
+			Name:                 "admonition",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"github": {
+					Plugins: []md.Plugin{
+						plugin.Admonition(&plugin.AdmonitionOptions{
+							Classes: map[string]string{"callout-box": "important"},
+						}),
+					},
+				},
+				"obsidian": {
+					Plugins: []md.Plugin{
+						plugin.Admonition(&plugin.AdmonitionOptions{Style: "obsidian"}),
+					},
+				},
+				"mkdocs": {
+					Plugins: []md.Plugin{
+						plugin.Admonition(&plugin.AdmonitionOptions{Style: "mkdocs"}),
+					},
+				},
+			},
+		},
+		{
+			Name:                 "details",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"html": {
+					Plugins: []md.Plugin{
+						plugin.Details(nil),
+					},
+				},
+				"bold": {
+					Plugins: []md.Plugin{
+						plugin.Details(&plugin.DetailsOptions{Style: "bold"}),
+					},
+				},
+				"removeclosed": {
+					Plugins: []md.Plugin{
+						plugin.Details(&plugin.DetailsOptions{RemoveClosed: true}),
+					},
+				},
+			},
+		},
+		{
+			Name:                 "toc",
+			GoldmarkPerVariation: true,
+			Variations: map[string]Variation{
+				"default": {
+					Plugins: []md.Plugin{
+						plugin.TableOfContents(nil),
+					},
+				},
+				"levels": {
+					Plugins: []md.Plugin{
+						plugin.TableOfContents(&plugin.TOCOptions{MinLevel: 2, MaxLevel: 3, ReplaceExisting: true}),
+					},
+				},
+				"heading_ids": {
+					Options: &md.Options{HeadingIDStyle: "attribute"},
+					Plugins: []md.Plugin{
+						plugin.TableOfContents(&plugin.TOCOptions{ReplaceExisting: true}),
+					},
+				},
+			},
+		},
+		{
+			Name: "math",
+			Variations: map[string]Variation{
+				"default": {
+					Plugins: []md.Plugin{
+						plugin.Math(),
+					},
+				},
+			},
+		},
 		{
 			Name: "movefrontmatter/simple",
 			Variations: map[string]Variation{
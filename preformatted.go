@@ -0,0 +1,217 @@
+package md
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/firecrawl/html-to-markdown/escape"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The white-space mode ("pre" or "pre-line") of an element
+// whose line breaks and indentation are preserved.
+var attrPreformatted = "data-converter-preformatted"
+
+const (
+	whiteSpacePre     = "pre"
+	whiteSpacePreLine = "pre-line"
+)
+
+// defaultPreformattedClasses are the utility classes (e.g. of Tailwind)
+// that change the white-space of an element.
+var defaultPreformattedClasses = map[string]string{
+	"whitespace-pre":          whiteSpacePre,
+	"whitespace-pre-wrap":     whiteSpacePre,
+	"whitespace-break-spaces": whiteSpacePre,
+	"whitespace-pre-line":     whiteSpacePreLine,
+	"text-pre-wrap":           whiteSpacePre,
+	"pre-wrap":                whiteSpacePre,
+	"preformatted":            whiteSpacePre,
+}
+
+// preformattedCodeBlockElements become a code block with the "codeblock" style.
+// Other preformatted elements (e.g. `<li>` or `<td>`) keep their line breaks instead.
+var preformattedCodeBlockElements = []string{"p", "div", "section", "article", "aside", "xmp", "listing", "plaintext"}
+
+var preformattedCodeBlockSelector = strings.Join(preformattedCodeBlockElements, ", ")
+
+var whiteSpaceStyleR = regexp.MustCompile(`(?i)(?:^|;)\s*white-space\s*:\s*([a-z-]+)`)
+
+var multipleSpacesOrIndentR = regexp.MustCompile(` +`)
+
+// annotatePreformatted marks the elements that preserve their line breaks
+// and indentation: an inline `white-space` style, one of the `PreformattedClasses`,
+// `<xmp>`, `<listing>`, `<plaintext>` and `<textarea>`.
+func annotatePreformatted(selec *goquery.Selection, opt *Options) {
+	if opt.PreformattedStyle == "collapse" {
+		return
+	}
+
+	classes := make(map[string]string)
+	for class, mode := range defaultPreformattedClasses {
+		classes[class] = mode
+	}
+	for _, class := range opt.PreformattedClasses {
+		classes[class] = whiteSpacePre
+	}
+
+	selec.Find("xmp, listing, plaintext, textarea, [style], [class]").Each(func(i int, s *goquery.Selection) {
+		mode := preformattedMode(s, classes)
+		if mode == "" || s.Closest("pre, code").Length() > 0 {
+			return
+		}
+
+		if s.Is("textarea") {
+			// textareas are removed, but the content is visible
+			n := s.Get(0)
+			n.Data = "div"
+			n.DataAtom = atom.Div
+		}
+		s.SetAttr(attrPreformatted, mode)
+		trimPreformatted(s.Get(0))
+	})
+}
+
+func preformattedMode(s *goquery.Selection, classes map[string]string) string {
+	if s.Is("xmp, listing, plaintext, textarea") {
+		return whiteSpacePre
+	}
+	if m := whiteSpaceStyleR.FindStringSubmatch(s.AttrOr("style", "")); m != nil {
+		switch strings.ToLower(m[1]) {
+		case "pre", "pre-wrap", "break-spaces":
+			return whiteSpacePre
+		case "pre-line":
+			return whiteSpacePreLine
+		}
+		// e.g. "normal" or "nowrap" overwrite a class
+		return ""
+	}
+	for _, class := range strings.Fields(s.AttrOr("class", "")) {
+		if mode, ok := classes[class]; ok {
+			return mode
+		}
+	}
+	return ""
+}
+
+// trimPreformatted removes the line breaks at the beginning and
+// the end, that are only there to format the html.
+func trimPreformatted(n *html.Node) {
+	var texts []*html.Node
+	var collect func(n *html.Node)
+	collect = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				texts = append(texts, c)
+			}
+			collect(c)
+		}
+	}
+	collect(n)
+	if len(texts) == 0 {
+		return
+	}
+
+	first := texts[0]
+	first.Data = strings.TrimLeft(first.Data, "\r\n")
+	last := texts[len(texts)-1]
+	last.Data = strings.TrimRight(last.Data, " \t\r\n")
+}
+
+// closestPreformatted returns the white-space mode of the closest preformatted element.
+func closestPreformatted(selec *goquery.Selection) (*goquery.Selection, string) {
+	for n := selec.Get(0).Parent; n != nil; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		for _, a := range n.Attr {
+			if a.Key == attrPreformatted {
+				return goquery.NewDocumentFromNode(n).Selection, a.Val
+			}
+		}
+	}
+	return nil, ""
+}
+
+// preformattedText keeps the line breaks of the text with hard line breaks.
+// For the "pre" mode the spaces are also kept with non-breaking spaces.
+// The text only starts at the beginning of a line if `atLineStart` is true.
+func preformattedText(text string, mode string, atLineStart bool, opt *Options) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if mode == whiteSpacePre {
+		text = strings.ReplaceAll(text, "\t", "    ")
+	} else {
+		text = tabR.ReplaceAllString(text, " ")
+		text = multipleSpacesR.ReplaceAllString(text, " ")
+	}
+
+	if opt.EscapeMode == "basic" {
+		text = escape.MarkdownCharacters(text)
+	}
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lineStart := i > 0 || atLineStart
+		if mode == whiteSpacePreLine {
+			if lineStart {
+				line = strings.TrimLeft(line, " ")
+			}
+			if i < len(lines)-1 {
+				line = strings.TrimRight(line, " ")
+			}
+			lines[i] = line
+			continue
+		}
+		lines[i] = preserveSpaces(line, lineStart)
+	}
+
+	// an empty line would end the paragraph, so every line gets a hard line break
+	return strings.Join(lines, HardLineBreak(opt))
+}
+
+// preserveSpaces replaces the indentation and the runs of spaces
+// with non-breaking spaces, which are not collapsed by the browser.
+func preserveSpaces(line string, lineStart bool) string {
+	var b strings.Builder
+	var last int
+	for _, loc := range multipleSpacesOrIndentR.FindAllStringIndex(line, -1) {
+		count := loc[1] - loc[0]
+		if count == 1 && !(lineStart && loc[0] == 0) {
+			continue
+		}
+		b.WriteString(line[last:loc[0]])
+		b.WriteString(strings.Repeat("&nbsp;", count))
+		last = loc[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
+
+// preformattedCodeBlock returns the text of the element as a fenced code block.
+func preformattedCodeBlock(selec *goquery.Selection, opt *Options) string {
+	code := strings.ReplaceAll(selec.Text(), "\r\n", "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	if selec.AttrOr(attrPreformatted, "") == whiteSpacePreLine {
+		lines := strings.Split(code, "\n")
+		for i, line := range lines {
+			lines[i] = strings.Join(strings.Fields(line), " ")
+		}
+		code = strings.Join(lines, "\n")
+	}
+
+	return codeBlock(code, "", opt)
+}
+
+// codeBlock returns the code block for the `<pre>` elements
+// and the preformatted text, with the `Fence` of the options.
+func codeBlock(code, language string, opt *Options) string {
+	fenceChar, _ := utf8.DecodeRuneInString(opt.Fence)
+	fence := CalculateCodeFence(fenceChar, code)
+
+	return "\n\n" + fence + language + "\n" + code + "\n" + fence + "\n\n"
+}
@@ -0,0 +1,173 @@
+package md_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	md "github.com/firecrawl/html-to-markdown"
+	"github.com/firecrawl/html-to-markdown/plugin"
+	"github.com/sebdah/goldie/v2"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type SpecExample struct {
+	Markdown string `json:"markdown"`
+	Example  int    `json:"example"`
+	Section  string `json:"section"`
+}
+
+// TestSpec runs the examples of the CommonMark (and GFM) spec in reverse:
+// The markdown of every example is rendered to html with goldmark, converted back
+// with the `Converter` and rendered again. The example passes if both renderings
+// are semantically equivalent.
+//
+// The pass rate per section is written to "testdata/TestSpec/*.report.golden" and
+// the examples that fail are tracked in "testdata/TestSpec/*.failing.golden".
+// Run `go test -run TestSpec -update` after improving the converter.
+func TestSpec(t *testing.T) {
+	suites := []struct {
+		Name    string
+		GFM     bool
+		Plugins []md.Plugin
+	}{
+		{Name: "commonmark"},
+		{Name: "gfm", GFM: true, Plugins: []md.Plugin{plugin.GitHubFlavored()}},
+	}
+
+	for _, suite := range suites {
+		t.Run(suite.Name, func(t *testing.T) {
+			data, err := os.ReadFile(path.Join("testdata", "TestSpec", suite.Name+".json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var examples []SpecExample
+			if err := json.Unmarshal(data, &examples); err != nil {
+				t.Fatal(err)
+			}
+
+			gold := goldmark.New(goldmark.WithRendererOptions(html.WithUnsafe()))
+			if suite.GFM {
+				gold = goldmark.New(goldmark.WithRendererOptions(html.WithUnsafe()), goldmark.WithExtensions(extension.GFM))
+			}
+			render := func(markdown string) string {
+				var buf bytes.Buffer
+				if err := gold.Convert([]byte(markdown), &buf); err != nil {
+					t.Fatal(err)
+				}
+				return buf.String()
+			}
+
+			conv := md.NewConverter("", true, &md.Options{CodeBlockStyle: "fenced"})
+			conv.Use(suite.Plugins...)
+
+			var sections []string
+			passed := make(map[string]int)
+			total := make(map[string]int)
+			var failing strings.Builder
+
+			for _, example := range examples {
+				if total[example.Section] == 0 {
+					sections = append(sections, example.Section)
+				}
+				total[example.Section]++
+
+				expected := render(example.Markdown)
+				markdown, err := conv.ConvertString(expected)
+				if err != nil {
+					t.Fatal(err)
+				}
+				actual := render(markdown)
+
+				if normalizeHTML(t, expected) == normalizeHTML(t, actual) {
+					passed[example.Section]++
+					continue
+				}
+
+				fmt.Fprintf(&failing, "## Example %d (%s)\n\n", example.Example, example.Section)
+				fmt.Fprintf(&failing, "markdown:\n%s\nconverted:\n%s\n\n", example.Markdown, markdown)
+			}
+
+			sort.Strings(sections)
+
+			var report strings.Builder
+			var passedAll int
+			for _, section := range sections {
+				passedAll += passed[section]
+				fmt.Fprintf(&report, "%-45s %3d/%3d (%3d%%)\n", section, passed[section], total[section], 100*passed[section]/total[section])
+			}
+			fmt.Fprintf(&report, "%-45s %3d/%3d (%3d%%)\n", "Total", passedAll, len(examples), 100*passedAll/len(examples))
+			t.Log("\n" + report.String())
+
+			g := goldie.New(t)
+			g.Assert(t, path.Join("TestSpec", suite.Name+".report"), []byte(report.String()))
+			g.Assert(t, path.Join("TestSpec", suite.Name+".failing"), []byte(failing.String()))
+		})
+	}
+}
+
+// normalizeHTML returns a representation of the html that only contains
+// what is semantically relevant: the elements with some of their
+// attributes and the text (with collapsed whitespace outside of code blocks).
+func normalizeHTML(t *testing.T, rawHTML string) string {
+	nodes, err := nethtml.ParseFragment(strings.NewReader(rawHTML), &nethtml.Node{
+		Type:     nethtml.ElementNode,
+		Data:     "body",
+		DataAtom: atom.Body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	var walk func(n *nethtml.Node, pre bool)
+	walk = func(n *nethtml.Node, pre bool) {
+		switch n.Type {
+		case nethtml.TextNode:
+			text := n.Data
+			if !pre {
+				text = strings.Join(strings.Fields(text), " ")
+			}
+			if text != "" {
+				b.WriteString(text + "\n")
+			}
+			return
+		case nethtml.ElementNode:
+			// tight and loose lists are the same for our purpose
+			if n.Data == "p" && n.Parent != nil && n.Parent.Data == "li" {
+				break
+			}
+
+			b.WriteString("<" + n.Data)
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "href", "src", "alt", "title", "start", "align", "checked", "type":
+					fmt.Fprintf(&b, " %s=%q", attr.Key, attr.Val)
+				}
+			}
+			b.WriteString(">\n")
+			defer b.WriteString("</" + n.Data + ">\n")
+
+			if n.Data == "pre" {
+				pre = true
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, pre)
+		}
+	}
+	for _, n := range nodes {
+		walk(n, false)
+	}
+	return b.String()
+}
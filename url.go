@@ -0,0 +1,197 @@
+package md
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// URLKind is the kind of element that the url belongs to.
+type URLKind string
+
+const (
+	// URLKindAnchor is the href of a link.
+	URLKindAnchor URLKind = "anchor"
+	// URLKindImage is the src of an image.
+	URLKindImage URLKind = "image"
+	// URLKindIframe is the src of an iframe.
+	URLKindIframe URLKind = "iframe"
+)
+
+// URLTransformer rewrites the url of an element. It gets the absolute url
+// and returns the new url. Returning an empty string removes the url: a link
+// is replaced by its text and an image or iframe is removed.
+type URLTransformer func(selec *goquery.Selection, rawURL string, kind URLKind) string
+
+// unsafeSchemes can execute code when the link is clicked.
+var unsafeSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"livescript": true,
+	"data":       true,
+}
+
+// SafeURL removes urls with schemes that can execute code like "javascript:".
+// Data uris are only allowed for images. It runs before and after every one of
+// the `URLTransformers` unless `AllowUnsafeURLs` is enabled.
+func SafeURL(selec *goquery.Selection, rawURL string, kind URLKind) string {
+	// browsers ignore whitespace and control characters inside of the scheme
+	cleaned := strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, rawURL)
+
+	i := strings.IndexByte(cleaned, ':')
+	if i == -1 {
+		return rawURL
+	}
+	scheme := strings.ToLower(cleaned[:i])
+	if !unsafeSchemes[scheme] {
+		return rawURL
+	}
+	if scheme == "data" && kind == URLKindImage && strings.HasPrefix(strings.ToLower(cleaned), "data:image/") {
+		return rawURL
+	}
+	return ""
+}
+
+// defaultTrackingParameters are removed by `RemoveTrackingParameters`.
+// A trailing "*" matches every parameter with that prefix.
+var defaultTrackingParameters = []string{
+	"utm_*", "fbclid", "gclid", "dclid", "gclsrc", "msclkid", "yclid", "twclid",
+	"igshid", "mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "mkt_tok",
+	"oly_anon_id", "oly_enc_id", "vero_id", "wickedid",
+}
+
+// RemoveTrackingParameters returns a transformer that removes tracking parameters
+// (e.g. "utm_source" or "fbclid") from the query. The `params` are removed in
+// addition to the default parameters.
+func RemoveTrackingParameters(params ...string) URLTransformer {
+	params = append(params, defaultTrackingParameters...)
+
+	isTracking := func(key string) bool {
+		key = strings.ToLower(key)
+		for _, p := range params {
+			p = strings.ToLower(p)
+			if strings.HasSuffix(p, "*") && strings.HasPrefix(key, p[:len(p)-1]) {
+				return true
+			}
+			if key == p {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(selec *goquery.Selection, rawURL string, kind URLKind) string {
+		u, err := url.Parse(rawURL)
+		if err != nil || u.RawQuery == "" {
+			return rawURL
+		}
+
+		// the order of the other parameters stays the same
+		var kept []string
+		for _, part := range strings.Split(u.RawQuery, "&") {
+			key := part
+			if i := strings.IndexByte(part, '='); i != -1 {
+				key = part[:i]
+			}
+			if unescaped, err := url.QueryUnescape(key); err == nil {
+				key = unescaped
+			}
+			if part != "" && !isTracking(key) {
+				kept = append(kept, part)
+			}
+		}
+
+		u.RawQuery = strings.Join(kept, "&")
+		u.ForceQuery = false
+		return u.String()
+	}
+}
+
+// UnwrapRedirects is a transformer that replaces the urls of redirect services
+// with the url they redirect to, for example "https://www.google.com/url?q=..."
+// and "https://l.facebook.com/l.php?u=...".
+func UnwrapRedirects(selec *goquery.Selection, rawURL string, kind URLKind) string {
+	// the redirects can also be nested
+	for i := 0; i < 5; i++ {
+		target := redirectTarget(rawURL)
+		if target == "" {
+			break
+		}
+		rawURL = target
+	}
+	return rawURL
+}
+
+func redirectTarget(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	query := u.Query()
+
+	var target string
+	switch {
+	case strings.HasPrefix(host, "google.") && u.Path == "/url":
+		target = query.Get("q")
+		if target == "" {
+			target = query.Get("url")
+		}
+	case (host == "l.facebook.com" || host == "lm.facebook.com" || host == "facebook.com") && u.Path == "/l.php":
+		target = query.Get("u")
+	case host == "l.instagram.com" && u.Path == "/":
+		target = query.Get("u")
+	case host == "out.reddit.com":
+		target = query.Get("url")
+	}
+
+	// only follow redirects to absolute urls
+	t, err := url.Parse(target)
+	if err != nil || t.Scheme == "" || t.Host == "" {
+		return ""
+	}
+	return target
+}
+
+// RewriteURLPrefix returns a transformer that replaces the `prefix` of
+// the urls with the `replacement`, for example to link to a mirror.
+func RewriteURLPrefix(prefix, replacement string) URLTransformer {
+	return func(selec *goquery.Selection, rawURL string, kind URLKind) string {
+		if strings.HasPrefix(rawURL, prefix) {
+			return replacement + strings.TrimPrefix(rawURL, prefix)
+		}
+		return rawURL
+	}
+}
+
+// destinationReplacer escapes the whitespace that would end
+// the destination of a markdown link or image.
+var destinationReplacer = strings.NewReplacer(" ", "%20", "\t", "%09", "\n", "%0A", "\r", "%0D")
+
+// TransformURL makes the url absolute and passes it through the `URLTransformers`,
+// like the rules for links, images and iframes do. Plugins that write urls should use it.
+// `SafeURL` also checks the result of every transformer, since they can produce
+// a new url (e.g. `UnwrapRedirects`). An empty string is returned if the url was removed.
+func TransformURL(selec *goquery.Selection, rawURL string, kind URLKind, opt *Options) string {
+	rawURL = opt.GetAbsoluteURL(selec, rawURL, opt.domain)
+
+	if !opt.AllowUnsafeURLs {
+		rawURL = SafeURL(selec, rawURL, kind)
+	}
+	for _, transform := range opt.URLTransformers {
+		if rawURL == "" {
+			break
+		}
+		rawURL = transform(selec, rawURL, kind)
+		if !opt.AllowUnsafeURLs {
+			rawURL = SafeURL(selec, rawURL, kind)
+		}
+	}
+	return rawURL
+}
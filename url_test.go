@@ -0,0 +1,83 @@
+package md
+
+import "testing"
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url      string
+		kind     URLKind
+		expected string
+	}{
+		{"https://example.com", URLKindAnchor, "https://example.com"},
+		{"/relative/page.html", URLKindAnchor, "/relative/page.html"},
+		{"mailto:hi@example.com", URLKindAnchor, "mailto:hi@example.com"},
+		{"javascript:alert(1)", URLKindAnchor, ""},
+		{" JavaScript:alert(1)", URLKindAnchor, ""},
+		{"java\tscript:alert(1)", URLKindAnchor, ""},
+		{"vbscript:msgbox", URLKindAnchor, ""},
+		{"data:text/html,<script>alert(1)</script>", URLKindAnchor, ""},
+		{"data:image/png;base64,iVBORw0KGgo=", URLKindImage, "data:image/png;base64,iVBORw0KGgo="},
+		{"data:image/png;base64,iVBORw0KGgo=", URLKindAnchor, ""},
+		{"data:text/html,<p>hi</p>", URLKindIframe, ""},
+	}
+	for _, test := range tests {
+		if actual := SafeURL(nil, test.url, test.kind); actual != test.expected {
+			t.Errorf("SafeURL(%q, %s): expected %q but got %q", test.url, test.kind, test.expected, actual)
+		}
+	}
+}
+
+func TestRemoveTrackingParameters(t *testing.T) {
+	transform := RemoveTrackingParameters("ref")
+
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://example.com/page", "https://example.com/page"},
+		{"https://example.com/page?utm_source=news&utm_medium=email", "https://example.com/page"},
+		{"https://example.com/page?b=2&fbclid=abc&a=1#section", "https://example.com/page?b=2&a=1#section"},
+		{"https://example.com/page?ref=home&q=go", "https://example.com/page?q=go"},
+		{"https://example.com/page?UTM_Campaign=x&q=a%20b", "https://example.com/page?q=a%20b"},
+	}
+	for _, test := range tests {
+		if actual := transform(nil, test.url, URLKindAnchor); actual != test.expected {
+			t.Errorf("RemoveTrackingParameters(%q): expected %q but got %q", test.url, test.expected, actual)
+		}
+	}
+}
+
+func TestUnwrapRedirects(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://www.google.com/url?q=https://example.com/page&sa=D", "https://example.com/page"},
+		{"https://www.google.de/url?url=https%3A%2F%2Fexample.com%2F%3Fa%3D1", "https://example.com/?a=1"},
+		{"https://l.facebook.com/l.php?u=https%3A%2F%2Fexample.com%2F&h=AT0", "https://example.com/"},
+		{"https://www.google.com/url?q=/relative", "https://www.google.com/url?q=/relative"},
+		{"https://www.google.com/search?q=https://example.com", "https://www.google.com/search?q=https://example.com"},
+		// nested redirects
+		{"https://www.google.com/url?q=https%3A%2F%2Fl.facebook.com%2Fl.php%3Fu%3Dhttps%253A%252F%252Fexample.com%252F", "https://example.com/"},
+	}
+	for _, test := range tests {
+		if actual := UnwrapRedirects(nil, test.url, URLKindAnchor); actual != test.expected {
+			t.Errorf("UnwrapRedirects(%q): expected %q but got %q", test.url, test.expected, actual)
+		}
+	}
+}
+
+func TestTransformURLUnsafeRedirect(t *testing.T) {
+	input := `<p><a href="https://www.google.com/url?q=javascript://x/%250aalert(1)">click</a></p>`
+
+	conv := NewConverter("", true, &Options{
+		URLTransformers: []URLTransformer{UnwrapRedirects},
+	})
+	markdown, err := conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if markdown != "click" {
+		t.Errorf("expected the unsafe redirect target to be removed but got %q", markdown)
+	}
+}
@@ -3,7 +3,6 @@ package md
 import (
 	"bytes"
 	"fmt"
-	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -63,7 +62,7 @@ func getPrevNodeText(node *html.Node) (string, bool) {
 		return "", false
 	}
 
-	for ; node != nil; node = node.PrevSibling {
+	for ; node != nil; node = outerSibling(node, true) {
 		text := CollectText(node)
 
 		name := getName(node)
@@ -89,7 +88,7 @@ func getNextNodeText(node *html.Node) (string, bool) {
 		return "", false
 	}
 
-	for ; node != nil; node = node.NextSibling {
+	for ; node != nil; node = outerSibling(node, false) {
 		text := CollectText(node)
 
 		name := getName(node)
@@ -121,7 +120,7 @@ func AddSpaceIfNessesary(selec *goquery.Selection, markdown string) string {
 	}
 	rootNode := selec.Nodes[0]
 
-	prev, hasPrev := getPrevNodeText(rootNode.PrevSibling)
+	prev, hasPrev := getPrevNodeText(outerSibling(rootNode, true))
 	if hasPrev {
 		lastChar, size := utf8.DecodeLastRuneInString(prev)
 		if size > 0 && !unicode.IsSpace(lastChar) {
@@ -129,7 +128,7 @@ func AddSpaceIfNessesary(selec *goquery.Selection, markdown string) string {
 		}
 	}
 
-	next, hasNext := getNextNodeText(rootNode.NextSibling)
+	next, hasNext := getNextNodeText(outerSibling(rootNode, false))
 	if hasNext {
 		firstChar, size := utf8.DecodeRuneInString(next)
 		if size > 0 && !unicode.IsSpace(firstChar) && !unicode.IsPunct(firstChar) {
@@ -140,6 +139,38 @@ func AddSpaceIfNessesary(selec *goquery.Selection, markdown string) string {
 	return markdown
 }
 
+// contentOnlyElements are inline elements that are converted to only their
+// content, so the text next to them is directly next to their content.
+var contentOnlyElements = map[string]bool{
+	"span": true, "font": true, "small": true, "big": true, "mark": true, "abbr": true,
+	"cite": true, "dfn": true, "time": true, "label": true, "u": true, "ins": true,
+}
+
+// outerSibling returns the previous (or next) sibling of the node. For the first
+// (or last) node inside of one of the `contentOnlyElements`, an unknown element,
+// a block element without a line break (e.g. a `<section>`) or a link without
+// href, it is the sibling of that element.
+func outerSibling(n *html.Node, previous bool) *html.Node {
+	for {
+		sibling := n.NextSibling
+		if previous {
+			sibling = n.PrevSibling
+		}
+		parent := n.Parent
+		if sibling != nil || parent == nil || parent.Type != html.ElementNode {
+			return sibling
+		}
+
+		_, hasHref := goquery.NewDocumentFromNode(parent).Attr("href")
+		isUnknown := parent.DataAtom == 0
+		isContentBlock := !IsInlineElement(parent.Data) && !lineBreakElements[parent.Data]
+		if !contentOnlyElements[parent.Data] && !isUnknown && !isContentBlock && !(parent.Data == "a" && !hasHref) {
+			return nil
+		}
+		n = parent
+	}
+}
+
 func isLineCodeDelimiter(chars []rune) bool {
 	if len(chars) < 3 {
 		return false
@@ -218,15 +249,23 @@ func TrimTrailingSpaces(text string) string {
 	return strings.Join(parts, "\n")
 }
 
-// The same as `multipleNewLinesRegex`, but applies to escaped new lines inside a link `\n\`
-var multipleNewLinesInLinkRegex = regexp.MustCompile(`(\n\\){1,}`) // `([\n\r\s]\\)`
+// QuoteBlock prefixes every line of the content with "> ", like the
+// rule for `<blockquote>` does. The content should already be trimmed.
+func QuoteBlock(content string) string {
+	content = multipleNewLinesRegex.ReplaceAllString(content, "\n\n")
+
+	return beginningOfLineR.ReplaceAllString(content, "> ")
+}
 
 // EscapeMultiLine deals with multiline content inside a link
 func EscapeMultiLine(content string) string {
 	content = strings.TrimSpace(content)
-	content = strings.Replace(content, "\n", `\`+"\n", -1)
 
-	content = multipleNewLinesInLinkRegex.ReplaceAllString(content, "\n\\")
+	// Reduce the new lines before escaping them. Otherwise an escaped
+	// character at the start of a line (e.g. `\|`) could be mistaken for
+	// an escaped new line.
+	content = multipleNewLinesRegex.ReplaceAllString(content, "\n\n")
+	content = strings.Replace(content, "\n", `\`+"\n", -1)
 
 	return content
 }
@@ -386,6 +425,9 @@ func getListStart(parent *goquery.Selection) int {
 // IndentMultiLineListItem makes sure that multiline list items
 // are properly indented.
 func IndentMultiLineListItem(opt *Options, text string, spaces int) string {
+	indent := strings.Repeat(" ", spaces)
+
+	var inNestedList bool
 	parts := strings.Split(text, "\n")
 	for i := range parts {
 		// dont touch the first line since its indented through the prefix
@@ -393,11 +435,16 @@ func IndentMultiLineListItem(opt *Options, text string, spaces int) string {
 			continue
 		}
 
+		// the nested list is already indented, but the content
+		// after it (e.g. "<li>a<ul>...</ul>b</li>") is not
 		if isListItem(opt, parts[i]) {
-			return strings.Join(parts, "\n")
+			inNestedList = true
+			continue
+		}
+		if inNestedList && (strings.HasPrefix(parts[i], indent) || strings.TrimSpace(parts[i]) == "") {
+			continue
 		}
 
-		indent := strings.Repeat(" ", spaces)
 		parts[i] = indent + parts[i]
 	}
 
@@ -611,11 +658,16 @@ func annotateListIndentation(root *goquery.Selection, opt *Options) {
 			}
 
 			lastIndex := elemCount
-			maxLen := len(strconv.Itoa(lastIndex))
 			start := 1
 			if strings.EqualFold(n.Data, "ol") {
 				start = parseListStart(n)
 			}
+			// markdown only allows numbers with up to 9 digits
+			if start+lastIndex-1 > 999999999 {
+				start = 1
+			}
+			// the number of the last item is the widest (e.g. "99." and "100.")
+			maxLen := len(strconv.Itoa(start + lastIndex - 1))
 
 			// Determine reserved prefix length for this list level from the first <li>.
 			prefixLen := 0
@@ -0,0 +1,378 @@
+package md
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+	nethtml "golang.org/x/net/html"
+)
+
+// VerifyOptions configures the round-trip check of `Verify`.
+type VerifyOptions struct {
+	// GFM parses the markdown with GitHub's Flavored Markdown extensions
+	// (tables, strikethrough, ...). Enable it if the converter uses `plugin.GitHubFlavored`.
+	GFM bool
+
+	// Domain is used to convert relative urls of the original html to absolute
+	// urls, the same way the converter does. It should be the domain that
+	// was passed to `NewConverter`.
+	Domain string
+
+	// Options are the options of the converter. They are used to choose the
+	// urls of the original html the same way as the converter: the source of
+	// lazy and responsive images, the `URLTransformers`, `SafeURL`, ...
+	// The `AssetHandler` is not called.
+	Options *Options
+}
+
+// StructureDiff is the number of times a kind of element
+// (e.g. "heading" or "list") appears in the original html
+// and in the html rendered from the markdown.
+type StructureDiff struct {
+	Element  string
+	Original int
+	Rendered int
+}
+
+// VerifyReport is returned by `Verify` and lists the content
+// that got lost during the conversion.
+type VerifyReport struct {
+	// LostText are the words of the original html that are missing
+	// from the rendered markdown.
+	LostText []string
+
+	// LostLinks are the hrefs of links that don't exist anymore after
+	// rendering the markdown, for example because the link syntax broke.
+	LostLinks []string
+
+	// LostImages are the srcs of images that don't exist anymore after
+	// rendering the markdown.
+	LostImages []string
+
+	// Structure lists the kinds of elements that appear a different number of times.
+	// This is informational since some differences are expected (e.g. a `<div>` soup
+	// that was converted into paragraphs) and does not influence `OK`.
+	Structure []StructureDiff
+}
+
+// OK reports whether no text, links or images got lost.
+func (r *VerifyReport) OK() bool {
+	return len(r.LostText) == 0 && len(r.LostLinks) == 0 && len(r.LostImages) == 0
+}
+
+func (r *VerifyReport) String() string {
+	var b strings.Builder
+	if len(r.LostText) > 0 {
+		fmt.Fprintf(&b, "lost text: %q\n", r.LostText)
+	}
+	if len(r.LostLinks) > 0 {
+		fmt.Fprintf(&b, "lost links: %q\n", r.LostLinks)
+	}
+	if len(r.LostImages) > 0 {
+		fmt.Fprintf(&b, "lost images: %q\n", r.LostImages)
+	}
+	for _, diff := range r.Structure {
+		fmt.Fprintf(&b, "%s: %d in the original but %d rendered\n", diff.Element, diff.Original, diff.Rendered)
+	}
+	return b.String()
+}
+
+// Verify parses the `markdown` (that was generated from `originalHTML`) with goldmark,
+// renders it back to html and compares it with the original html.
+//
+// It can be used to catch escaping regressions, for example text that is
+// suddenly interpreted as markdown syntax or links that don't parse anymore.
+func Verify(originalHTML string, markdown string, opt *VerifyOptions) (*VerifyReport, error) {
+	if opt == nil {
+		opt = &VerifyOptions{}
+	}
+
+	original, err := goquery.NewDocumentFromReader(strings.NewReader(originalHTML))
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderMarkdown(markdown, opt.GFM)
+	if err != nil {
+		return nil, err
+	}
+	renderedDoc, err := goquery.NewDocumentFromReader(strings.NewReader(rendered))
+	if err != nil {
+		return nil, err
+	}
+
+	var convOpt Options
+	if opt.Options != nil {
+		convOpt = *opt.Options
+	}
+	convOpt.domain = opt.Domain
+	if convOpt.GetAbsoluteURL == nil {
+		convOpt.GetAbsoluteURL = DefaultGetAbsoluteURL
+	}
+	if convOpt.HeadingIDStyle == "" {
+		convOpt.HeadingIDStyle = "none"
+	}
+
+	before := collectVerifyData(original.Selection, &convOpt)
+	after := collectVerifyData(renderedDoc.Selection, nil)
+
+	report := &VerifyReport{
+		LostText:   lostWords(before.words, after.words),
+		LostLinks:  lostItems(before.links, after.links),
+		LostImages: lostItems(before.images, after.images),
+	}
+
+	var elements []string
+	for element := range before.structure {
+		elements = append(elements, element)
+	}
+	for element := range after.structure {
+		if _, ok := before.structure[element]; !ok {
+			elements = append(elements, element)
+		}
+	}
+	sort.Strings(elements)
+	for _, element := range elements {
+		if before.structure[element] != after.structure[element] {
+			report.Structure = append(report.Structure, StructureDiff{
+				Element:  element,
+				Original: before.structure[element],
+				Rendered: after.structure[element],
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// renderMarkdown renders the markdown to html using goldmark. Raw html
+// inside the markdown (e.g. from `Keep`) is rendered as well.
+func renderMarkdown(markdown string, gfm bool) (string, error) {
+	options := []goldmark.Option{
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	}
+	if gfm {
+		options = append(options, goldmark.WithExtensions(extension.GFM))
+	}
+
+	var buf bytes.Buffer
+	if err := goldmark.New(options...).Convert([]byte(markdown), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+type verifyData struct {
+	words     []string
+	links     []string
+	images    []string
+	structure map[string]int
+}
+
+// verifyStructure maps elements to the kind of structure they represent.
+var verifyStructure = map[string]string{
+	"h1": "heading", "h2": "heading", "h3": "heading",
+	"h4": "heading", "h5": "heading", "h6": "heading",
+	"ul": "list", "ol": "list",
+	"li":         "list item",
+	"blockquote": "blockquote",
+	"pre":        "code block",
+	"table":      "table",
+	"hr":         "thematic break",
+}
+
+// collectVerifyData collects the text, links and images of the html. For the
+// original html `opt` are the options of the converter, that choose and filter the
+// urls like the rules do. The rendered html is collected without options.
+func collectVerifyData(selec *goquery.Selection, opt *Options) verifyData {
+	var domain string
+	if opt != nil {
+		domain = opt.domain
+	}
+
+	data := verifyData{
+		structure: make(map[string]int),
+	}
+	var text strings.Builder
+
+	var walk func(n *nethtml.Node, insideCode bool)
+	walk = func(n *nethtml.Node, insideCode bool) {
+		switch n.Type {
+		case nethtml.TextNode:
+			text.WriteString(n.Data)
+			return
+		case nethtml.ElementNode:
+			switch n.Data {
+			case "script", "style", "textarea", "noscript", "template":
+				return
+			case "pre", "code":
+				insideCode = true
+			case "a":
+				href := strings.TrimSpace(attrValue(n, "href"))
+				if href != "" && href != "#" && !insideCode && isConvertedLink(n, href, domain) {
+					// the url is already absolute
+					if href = verifyURL(n, href, URLKindAnchor, opt); href != "" {
+						data.links = append(data.links, normalizeVerifyURL(href))
+					}
+				}
+			case "img":
+				src := strings.TrimSpace(attrValue(n, "src"))
+				if opt != nil {
+					// the url is already absolute
+					src = verifyImageSource(n, opt)
+				}
+				if src != "" {
+					data.images = append(data.images, normalizeVerifyURL(src))
+				}
+			}
+			if kind, ok := verifyStructure[n.Data]; ok {
+				data.structure[kind]++
+			}
+
+			if !IsInlineElement(n.Data) {
+				text.WriteString("\n")
+				defer text.WriteString("\n")
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, insideCode)
+		}
+	}
+	for _, n := range selec.Nodes {
+		walk(n, false)
+	}
+
+	data.words = strings.Fields(text.String())
+	return data
+}
+
+// verifyURL passes the url of the original html through the same
+// transformations as the rules. An empty string is returned if the
+// url is removed, for example a link with "javascript:".
+func verifyURL(n *nethtml.Node, rawURL string, kind URLKind, opt *Options) string {
+	if opt == nil {
+		return rawURL
+	}
+	if kind == URLKindAnchor && opt.LinkStyle == "text" {
+		return ""
+	}
+	selec := goquery.NewDocumentFromNode(n).Selection
+	if kind == URLKindAnchor {
+		if fragment, ok := fragmentLink(selec, rawURL, opt); ok {
+			return fragment
+		}
+	}
+	return TransformURL(selec, rawURL, kind, opt)
+}
+
+// verifyImageSource mirrors the `img` rule, which chooses the source of lazy
+// and responsive images and removes images for the "alt" and "none" styles.
+func verifyImageSource(n *nethtml.Node, opt *Options) string {
+	if opt.ImageStyle == "none" || opt.ImageStyle == "alt" {
+		return ""
+	}
+	src := imageSource(goquery.NewDocumentFromNode(n).Selection, opt.ImagePreferredWidth)
+	if src == "" {
+		return ""
+	}
+	src = verifyURL(n, src, URLKindImage, opt)
+	if src == "" {
+		return ""
+	}
+	return limitDataURI(src, opt)
+}
+
+func attrValue(n *nethtml.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// isConvertedLink mirrors the `a` rule, which drops links without any content
+// and links that only wrap an image pointing to the same url.
+func isConvertedLink(n *nethtml.Node, href string, domain string) bool {
+	if strings.TrimSpace(CollectText(n)) != "" {
+		return true
+	}
+	if attrValue(n, "title") != "" {
+		return true
+	}
+
+	var images []string
+	var find func(n *nethtml.Node)
+	find = func(n *nethtml.Node) {
+		if n.Type == nethtml.ElementNode && n.Data == "img" {
+			if src := strings.TrimSpace(attrValue(n, "src")); src != "" {
+				images = append(images, src)
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(n)
+
+	if len(images) == 1 {
+		return DefaultGetAbsoluteURL(nil, images[0], domain) != DefaultGetAbsoluteURL(nil, href, domain)
+	}
+	return len(images) > 0 || attrValue(n, "aria-label") != ""
+}
+
+// normalizeVerifyURL makes urls comparable, since goldmark
+// percent-encodes some characters of link destinations.
+func normalizeVerifyURL(rawURL string) string {
+	if unescaped, err := url.PathUnescape(rawURL); err == nil {
+		rawURL = unescaped
+	}
+	return rawURL
+}
+
+// lostItems returns the items of `before` that don't appear
+// (as often) in `after`.
+func lostItems(before, after []string) []string {
+	counts := make(map[string]int, len(after))
+	for _, item := range after {
+		counts[item]++
+	}
+
+	var lost []string
+	for _, item := range before {
+		if counts[item] > 0 {
+			counts[item]--
+			continue
+		}
+		lost = append(lost, item)
+	}
+	return lost
+}
+
+// lostWords is similar to `lostItems` but is more forgiving regarding
+// whitespace. The converter sometimes adds a space between inline elements
+// (e.g. "<b>a</b>b" becomes "**a** b") which would otherwise split a word.
+func lostWords(before, after []string) []string {
+	lost := lostItems(before, after)
+	if len(lost) == 0 {
+		return nil
+	}
+
+	compact := strings.Join(after, "")
+
+	var result []string
+	for _, word := range lost {
+		if !strings.Contains(compact, word) {
+			result = append(result, word)
+		}
+	}
+	return result
+}
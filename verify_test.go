@@ -0,0 +1,128 @@
+package md
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	input := `<h1>Title</h1><p>Some <strong>bold</strong> text with *stars* and a <a href="/page">link</a>.</p><img src="/image.png" alt="image">`
+
+	conv := NewConverter("example.com", true, nil)
+	markdown, err := conv.ConvertString(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Verify(input, markdown, &VerifyOptions{Domain: "example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("expected the report to be ok but got:\n%s", report)
+	}
+	if len(report.Structure) != 0 {
+		t.Errorf("expected no structure differences but got %+v", report.Structure)
+	}
+}
+
+func TestVerify_LostText(t *testing.T) {
+	input := `<p>*not emphasis*</p>`
+	markdown := `*not emphasis*` // missing the escaping
+
+	report, err := Verify(input, markdown, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"*not", "emphasis*"}
+	if !reflect.DeepEqual(report.LostText, expected) {
+		t.Errorf("expected %q but got %q", expected, report.LostText)
+	}
+	if report.OK() {
+		t.Error("expected the report not to be ok")
+	}
+}
+
+func TestVerify_LostLink(t *testing.T) {
+	input := `<p><a href="http://example.com/a b">link</a></p><p><img src="http://example.com/image.png"></p>`
+	markdown := "[link](http://example.com/a b)\n\n![](http://example.com/image.png"
+
+	report, err := Verify(input, markdown, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"http://example.com/a b"}; !reflect.DeepEqual(report.LostLinks, expected) {
+		t.Errorf("expected lost links %q but got %q", expected, report.LostLinks)
+	}
+	if expected := []string{"http://example.com/image.png"}; !reflect.DeepEqual(report.LostImages, expected) {
+		t.Errorf("expected lost images %q but got %q", expected, report.LostImages)
+	}
+}
+
+func TestVerify_Structure(t *testing.T) {
+	input := `<ul><li>one</li><li>two</li></ul>`
+	markdown := "- one\n\n* two"
+
+	report, err := Verify(input, markdown, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() {
+		t.Errorf("expected the report to be ok but got:\n%s", report)
+	}
+
+	expected := []StructureDiff{{Element: "list", Original: 1, Rendered: 2}}
+	if !reflect.DeepEqual(report.Structure, expected) {
+		t.Errorf("expected %+v but got %+v", expected, report.Structure)
+	}
+}
+
+func TestVerify_GFM(t *testing.T) {
+	input := `<p><del>old</del></p><table><tr><th>a</th></tr><tr><td>b</td></tr></table>`
+	markdown := "~~old~~\n\n| a |\n| --- |\n| b |"
+
+	report, err := Verify(input, markdown, &VerifyOptions{GFM: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK() || len(report.Structure) != 0 {
+		t.Errorf("expected the report to be ok but got:\n%s", report)
+	}
+
+	report, err = Verify(input, markdown, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []StructureDiff{{Element: "table", Original: 1, Rendered: 0}}
+	if !reflect.DeepEqual(report.Structure, expected) {
+		t.Errorf("expected %+v without GFM but got %+v", expected, report.Structure)
+	}
+}
+
+func TestVerify_ImageSourcesAndURLs(t *testing.T) {
+	for _, name := range []string{"image_lazy", "url_transform"} {
+		t.Run(name, func(t *testing.T) {
+			input, err := os.ReadFile(filepath.Join("testdata", "TestCommonmark", name, "input.html"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opt := &Options{ImagePreferredWidth: 800}
+			conv := NewConverter("example.com", true, opt)
+			markdown, err := conv.ConvertString(string(input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := Verify(string(input), markdown, &VerifyOptions{Domain: "example.com", Options: opt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.LostImages) != 0 || len(report.LostLinks) != 0 {
+				t.Errorf("expected no lost images or links but got:\n%s", report)
+			}
+		})
+	}
+}